@@ -0,0 +1,65 @@
+package conf
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Alias adds a deprecated env key and/or flag key (either may be "") still accepted for
+// the setting, for example Alias("HOST", "host") after renaming it to BIND_ADDR. The current
+// keys take precedence over the aliases, and using an alias writes a warning, or fails
+// the load in strict mode.
+func Alias(envKey, flagKey string) Option {
+	return func(s *setting) {
+		if envKey != "" {
+			s.envAliases = append(s.envAliases, envKey)
+		}
+		if flagKey != "" {
+			s.flagAliases = append(s.flagAliases, flagKey)
+		}
+	}
+}
+
+// SetStrict makes Load fail when a deprecated key is used instead of writing a warning.
+func (c *Config) SetStrict(strict bool) {
+	c.strict = strict
+}
+
+func SetStrict(strict bool) {
+	Default.SetStrict(strict)
+}
+
+// SetWarnings sets where warnings are written, os.Stderr by default.
+func (c *Config) SetWarnings(w io.Writer) {
+	c.warnings = w
+}
+
+func SetWarnings(w io.Writer) {
+	Default.SetWarnings(w)
+}
+
+func (c *Config) root() *Config {
+	if c.parent != nil {
+		return c.parent.root()
+	}
+	return c
+}
+
+func (c *Config) warn(format string, a ...any) {
+	w := c.root().warnings
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "conf: "+format+"\n", a...)
+}
+
+func (c *Config) deprecated(s *setting, envAlias, flagAlias, str string) error {
+	if c.root().strict {
+		fe := s.fieldError(str, fmt.Errorf("deprecated, use %s", describeKeys(s.envKey, s.flagKey)))
+		fe.Env, fe.Flag = envAlias, flagAlias
+		return fe
+	}
+	c.warn("%s is deprecated, use %s", describeKeys(envAlias, flagAlias), describeKeys(s.envKey, s.flagKey))
+	return nil
+}
@@ -0,0 +1,222 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/enolgor/go-utils/parse"
+	"github.com/enolgor/go-utils/parse/types"
+	"github.com/enolgor/go-utils/validators"
+)
+
+// Bind registers every exported field of the struct pointed by v that has an env or flag tag:
+//
+//	Port int `env:"PORT" flag:"p" default:"8080" validate:"min=10000"`
+//
+// The current field value is the default unless a default tag is given, a desc tag sets
+// the usage description and secret:"true" marks the field as Secret. Nested structs
+// prefix their fields' env keys with their env tag (or upper-cased field name) and "_",
+// and flag keys with their flag tag (or lower-cased field name) and "-". Embedded structs
+// are not prefixed. Validation rules are comma separated and run in order: min=N and
+// max=N for int, notempty, email, len=N and oneof=a b c for string, notempty and len=N
+// for types.HexBytes and max=SIZE and max=RATE for types.ByteSize and types.Rate.
+func (c *Config) Bind(v any) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("conf: Bind expects a pointer to a struct, got %T", v))
+	}
+	c.bindStruct(rv.Elem(), "", "")
+}
+
+func Bind(v any) {
+	Default.Bind(v)
+}
+
+func (c *Config) bindStruct(v reflect.Value, envPrefix, flagPrefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		envKey, flagKey := field.Tag.Get("env"), field.Tag.Get("flag")
+		ptr := v.Field(i).Addr().Interface()
+		if field.Type.Kind() == reflect.Struct && !isConfigurable(ptr) {
+			if field.Anonymous && envKey == "" && flagKey == "" {
+				c.bindStruct(v.Field(i), envPrefix, flagPrefix)
+				continue
+			}
+			if envKey == "" {
+				envKey = strings.ToUpper(field.Name)
+			}
+			if flagKey == "" {
+				flagKey = strings.ToLower(field.Name)
+			}
+			c.bindStruct(v.Field(i), envPrefix+envKey+"_", flagPrefix+flagKey+"-")
+			continue
+		}
+		if envKey == "" && flagKey == "" {
+			continue
+		}
+		if envKey != "" {
+			envKey = envPrefix + envKey
+		}
+		if flagKey != "" {
+			flagKey = flagPrefix + flagKey
+		}
+		if err := bindField(c, ptr, envKey, flagKey, field.Tag); err != nil {
+			panic(fmt.Sprintf("conf: cannot bind field %s.%s: %s", t.Name(), field.Name, err.Error()))
+		}
+	}
+}
+
+func isConfigurable(ptr any) bool {
+	_, _, ok := parse.Codec(reflect.TypeOf(ptr).Elem())
+	return ok
+}
+
+// bindField registers the field pointed by ptr, of any type supported by parse.Codec.
+func bindField(c *Config, ptr any, envKey, flagKey string, tag reflect.StructTag) error {
+	v := reflect.ValueOf(ptr).Elem()
+	parser, formatter, ok := parse.Codec(v.Type())
+	if !ok {
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	def := v.Interface()
+	if str, ok := tag.Lookup("default"); ok {
+		var err error
+		if def, err = parser(str); err != nil {
+			return fmt.Errorf(`invalid default "%s": %s`, str, err.Error())
+		}
+	}
+	s := &setting{
+		envKey:      envKey,
+		flagKey:     flagKey,
+		ptr:         ptr,
+		isBool:      v.Type() == reflect.TypeOf(false),
+		parse:       parser,
+		get:         func() any { return v.Interface() },
+		put:         func(x any) { setValue(v, x) },
+		formatValue: formatter,
+		defValue:    func() any { return def },
+		typeName:    v.Type().String(),
+		def:         formatter(def),
+	}
+	if str := tag.Get("validate"); str != "" {
+		if err := bindValidator(s, v.Type(), str); err != nil {
+			return err
+		}
+	}
+	setValue(v, def)
+	c.add(s, bindOptions(tag))
+	return nil
+}
+
+func setValue(v reflect.Value, x any) {
+	if x == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+	v.Set(reflect.ValueOf(x))
+}
+
+func bindOptions(tag reflect.StructTag) []Option {
+	opts := []Option{Description(tag.Get("desc"))}
+	if secret, _ := strconv.ParseBool(tag.Get("secret")); secret {
+		opts = append(opts, Secret)
+	}
+	return opts
+}
+
+// bindRule returns the validator of a rule given its argument.
+type bindRule func(arg string) (func(any) error, error)
+
+// bindRules are the validation rules of each type supported by Bind.
+var bindRules = map[reflect.Type]map[string]bindRule{
+	reflect.TypeOf(0): {
+		"min": argRule(validators.Ints.EqOrGreaterThan),
+		"max": argRule(validators.Ints.EqOrLessThan),
+	},
+	reflect.TypeOf(""): {
+		"notempty": plainRule(validators.Strings.NotEmpty),
+		"email":    plainRule(validators.Strings.ValidEmail),
+		"len":      argRule(validators.Strings.Len),
+		"oneof": func(arg string) (func(any) error, error) {
+			return typed(validators.Strings.OneOf(strings.Fields(arg)...)), nil
+		},
+	},
+	reflect.TypeOf(types.HexBytes{}): {
+		"notempty": plainRule(validators.HexBytes.NotEmpty),
+		"len":      argRule(validators.HexBytes.Len),
+	},
+	reflect.TypeOf(types.ByteSize(0)): {
+		"max": argRule(validators.ByteSizes.AtMost),
+	},
+	reflect.TypeOf(types.Rate{}): {
+		"max": argRule(validators.Rates.AtMost),
+	},
+}
+
+func typed[T any](validator func(*T) error) func(any) error {
+	return func(v any) error {
+		value := v.(T)
+		return validator(&value)
+	}
+}
+
+func plainRule[T any](validator func(*T) error) bindRule {
+	return func(string) (func(any) error, error) {
+		return typed(validator), nil
+	}
+}
+
+// argRule parses the rule argument as an A, like min=10 or max=1GiB.
+func argRule[A any, T any](validator func(A) func(*T) error) bindRule {
+	return func(arg string) (func(any) error, error) {
+		var a A
+		if err := parse.Parse(&a, arg); err != nil {
+			return nil, fmt.Errorf(`invalid rule argument "%s": %s`, arg, err.Error())
+		}
+		return typed(validator(a)), nil
+	}
+}
+
+// bindValidator sets the validator of s from the comma separated rules of a validate
+// tag, which run in the order they are written.
+func bindValidator(s *setting, t reflect.Type, str string) error {
+	rules, ok := bindRules[t]
+	if !ok {
+		return fmt.Errorf("validation not supported for type %s", t)
+	}
+	list := []func(any) error{}
+	for _, rule := range strings.Split(str, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		newValidator, ok := rules[name]
+		if !ok {
+			return fmt.Errorf(`unknown %s rule "%s"`, t, name)
+		}
+		validator, err := newValidator(arg)
+		if err != nil {
+			return err
+		}
+		if name == "oneof" {
+			s.enum = strings.Fields(arg)
+		}
+		list = append(list, validator)
+	}
+	s.validated = true
+	s.validate = func(v any) error {
+		var errs error
+		for _, validator := range list {
+			errs = errors.Join(errs, validator(v))
+		}
+		if errs != nil {
+			return s.fieldError(s.formatValue(v), errs)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package conf
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/enolgor/go-utils/sec"
+)
+
+type command struct {
+	name        string
+	description string
+	config      *Config
+	run         func(args []string) error
+}
+
+// Command registers a subcommand and returns its registry. Settings registered on it with
+// the Set*In functions are only accepted by that command, while the settings, files and
+// crypto of c are inherited, so global flags can be given before or after the command
+// name. RunArgs dispatches to run with the remaining positional arguments.
+func (c *Config) Command(name, description string, run func(args []string) error) *Config {
+	sub := New(flag.NewFlagSet(c.flags.Name()+" "+name, flag.ContinueOnError), c.lookupEnv)
+	sub.parent = c
+	c.commands = append(c.commands, &command{name, description, sub, run})
+	return sub
+}
+
+func Command(name, description string, run func(args []string) error) *Config {
+	return Default.Command(name, description, run)
+}
+
+// Run is like RunArgs with the command line arguments (os.Args[1:]).
+func (c *Config) Run() error {
+	return c.RunArgs(os.Args[1:])
+}
+
+// RunArgs parses the global flags in args up to the command name, loads the settings of
+// that command together with the inherited ones and calls its handler. Load errors are
+// returned as in LoadArgs and the handler is not called.
+func (c *Config) RunArgs(args []string) error {
+	c.mu.Lock()
+	c.registerFlags()
+	c.flagValues = map[string][]string{}
+	err := c.flags.Parse(args)
+	flagValues := c.flagValues
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	rest := c.flags.Args()
+	if len(rest) == 0 {
+		c.flags.Usage()
+		return errors.New("no command given")
+	}
+	for _, cmd := range c.commands {
+		if cmd.name != rest[0] {
+			continue
+		}
+		if err := cmd.config.loadArgs(rest[1:], flagValues); err != nil {
+			return err
+		}
+		return cmd.run(cmd.config.flags.Args())
+	}
+	c.flags.Usage()
+	return fmt.Errorf(`unknown command "%s"`, rest[0])
+}
+
+func Run() error {
+	return Default.Run()
+}
+
+func (c *Config) allSettings() []*setting {
+	if c.parent == nil {
+		return c.settings
+	}
+	return append(append([]*setting{}, c.parent.allSettings()...), c.settings...)
+}
+
+func (c *Config) allFiles() []string {
+	if c.parent == nil {
+		return c.files
+	}
+	return append(append([]string{}, c.parent.allFiles()...), c.files...)
+}
+
+func (c *Config) allRules() []func() error {
+	if c.parent == nil {
+		return c.rules
+	}
+	return append(append([]func() error{}, c.parent.allRules()...), c.rules...)
+}
+
+func (c *Config) cryptoOrInherited() sec.Crypto {
+	if c.crypto == nil && c.parent != nil {
+		return c.parent.cryptoOrInherited()
+	}
+	return c.crypto
+}
@@ -4,236 +4,501 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/enolgor/go-utils/parse"
+	"github.com/enolgor/go-utils/sec"
 )
 
-type KeyValue[K Configurable, V Configurable] struct {
-	Key   K
-	Value V
+// mustSupport panics unless T is a parse.Parseable type or any other type supported by
+// parse.Codec, such as the types registered with parse.Register or implementing
+// encoding.TextUnmarshaler. As registrations happen at run time the Set* functions cannot
+// constrain T to them, so they take any type and check it with mustSupport.
+func mustSupport[T any]() {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if _, _, ok := parse.Codec(t); !ok {
+		panic(fmt.Sprintf("conf: unsupported type %s", t))
+	}
 }
 
-type Configurable interface {
-	parse.Parseable
+type setting struct {
+	envKey      string
+	flagKey     string
+	ptr         any
+	isBool      bool
+	parse       func(string) (any, error)
+	get         func() any
+	put         func(any)
+	formatValue func(any) string
+	defValue    func() any
+	validate    func(any) error
+	onChange    []func(old, new any)
+	typeName    string
+	def         string
+	description string
+	secret      bool
+	validated   bool
+	repeatable  bool
+	envAliases  []string
+	flagAliases []string
+	enum        []string
+	publish     func()
+	source      Source
+	raw         string
+	profile     string
+	// value and origin are resolved by Load and Reload, and only committed to ptr (and to
+	// source, raw and profile) once the values are validated, being reverted if a rule fails
+	value  any
+	origin sourced
+	// profileDefaults holds the defaults of the setting for each profile
+	profileDefaults map[string]any
 }
 
-func identity(s string) (string, error) {
-	return s, nil
-}
+// Option customizes a setting when passed to any of the Set* functions.
+type Option func(*setting)
 
-func flagFunc[T Configurable](take *T, f func(string) (string, error)) func(string) error {
-	if f == nil {
-		f = identity
+// Description sets the text shown for the setting in the usage output.
+func Description(text string) Option {
+	return func(s *setting) {
+		s.description = text
 	}
-	return func(s string) error {
-		var err error
-		if s, err = f(s); err != nil {
-			return err
-		}
-		return parse.Parse(take, s)
+}
+
+// Enum lists the values of the setting in the enum of its Schema property, such as the
+// ones accepted by its validators.Strings.OneOf validator.
+func Enum(values ...string) Option {
+	return func(s *setting) {
+		s.enum = values
 	}
 }
 
-func chain(f1 func(string) error, f2 func(string) error) func(string) error {
-	return func(s string) error {
-		var err error
-		if err = f1(s); err != nil {
-			return err
-		}
-		return f2(s)
+func (s *setting) format() string {
+	return s.formatValue(s.get())
+}
+
+func (s *setting) fileKey() string {
+	if s.envKey != "" {
+		return normalizeKey(s.envKey)
 	}
+	return normalizeKey(s.flagKey)
 }
 
-func getEnv[T Configurable](take *T, key string, f func(string) (string, error)) (string, error) {
-	var err error
-	var str string
-	var ok bool
-	if f == nil {
-		f = identity
+type Config struct {
+	mu           sync.Mutex
+	flags        *flag.FlagSet
+	lookupEnv    func(string) (string, bool)
+	crypto       sec.Crypto
+	settings     []*setting
+	files        []string
+	dotenvFiles  []string
+	dotenv       map[string]string
+	flagValues   map[string][]string
+	rules        []func() error
+	parent       *Config
+	commands     []*command
+	profile      *setting
+	profileName  string
+	profileFiles map[string][]string
+	strict       bool
+	warnings     io.Writer
+	prefix       string
+	prefixStrict bool
+	environ      func() []string
+}
+
+// New creates an empty configuration registry that parses flags with the given flag set
+// and reads env vars with lookupEnv. A nil flag set defaults to a new one named after the
+// program and a nil lookupEnv defaults to os.LookupEnv (and SetEnviron to os.Environ).
+func New(flags *flag.FlagSet, lookupEnv func(string) (string, bool)) *Config {
+	if flags == nil {
+		flags = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 	}
-	if str, ok = os.LookupEnv(key); ok && str != "" {
-		if str, err = f(str); err != nil {
-			return str, err
-		}
-		err = parse.Parse(take, str)
+	var environ func() []string
+	if lookupEnv == nil {
+		lookupEnv, environ = os.LookupEnv, os.Environ
+	}
+	c := &Config{
+		flags:        flags,
+		lookupEnv:    lookupEnv,
+		environ:      environ,
+		settings:     []*setting{},
+		files:        []string{},
+		profileFiles: map[string][]string{},
+		flagValues:   map[string][]string{},
+		rules:        []func() error{},
 	}
-	return str, err
+	if flags != flag.CommandLine {
+		flags.Usage = c.printUsage
+	}
+	return c
 }
 
-var envErr error
+func (c *Config) printUsage() {
+	fmt.Fprintf(c.flags.Output(), "Usage of %s:\n", c.flags.Name())
+	c.Usage(c.flags.Output())
+}
 
-var flagFuncs map[string]func(string) error = map[string]func(string) error{}
-var boolFlags map[string]*bool = map[string]*bool{}
+// defaultUsage identifies the flag.Usage of the flag package, which Load only replaces
+// with the usage of the settings if the program has not set its own.
+var defaultUsage = reflect.ValueOf(flag.Usage).Pointer()
 
-func set[T Configurable](take *T, envKey, flagKey string, def T, f func(string) (string, error)) {
-	*take = def
-	if envKey != "" {
-		if val, err := getEnv(take, envKey, f); err != nil {
-			envErr = fmt.Errorf(`invalid value "%s" for env "%s": %s`, val, envKey, err.Error())
-		}
+// Default is the registry used by the package-level functions, bound to flag.CommandLine and the process env.
+var Default *Config = New(flag.CommandLine, nil)
+
+func (c *Config) add(s *setting, opts []Option) *setting {
+	for _, opt := range opts {
+		opt(s)
 	}
-	if flagKey != "" {
-		if v, ok := any(take).(*bool); ok && f == nil {
-			boolFlags[flagKey] = v
-		} else {
-			if fn, ok := flagFuncs[flagKey]; ok {
-				flagFuncs[flagKey] = chain(fn, flagFunc(take, f))
-			} else {
-				flagFuncs[flagKey] = flagFunc(take, f)
-			}
-		}
+	c.settings = append(c.settings, s)
+	return s
+}
+
+func newSetting[T any](take *T, envKey, flagKey string, def T, parser func(string) (T, error), formatter func(T) string) *setting {
+	return &setting{
+		envKey:  envKey,
+		flagKey: flagKey,
+		ptr:     take,
+		parse: func(str string) (any, error) {
+			return parser(str)
+		},
+		get:         func() any { return *take },
+		put:         func(v any) { *take = v.(T) },
+		formatValue: func(v any) string { return formatter(v.(T)) },
+		defValue:    func() any { return def },
+		typeName:    fmt.Sprintf("%T", def),
+		def:         formatter(def),
 	}
 }
 
-func setKVarray[K Configurable, V Configurable](take *[]*KeyValue[K, V], envKey, flagKey string, def []KeyValue[K, V], i int) {
-	//fmt.Printf("called i=%d\n", i)
-	if len(*take) < i+1 {
-		*take = append(*take, &KeyValue[K, V]{})
+func set[T any](c *Config, take *T, envKey, flagKey string, def T, opts ...Option) *setting {
+	mustSupport[T]()
+	*take = def
+	if envKey == "" && flagKey == "" {
+		return nil
 	}
-	set[K](&((*take)[i].Key), envKey, flagKey, def[0].Key, func(str string) (string, error) {
-		parts := strings.Split(str, ",")
-		//fmt.Printf("set=%d,len=%d\n", i, len(parts))
-		if len(parts) != i+1 {
-			//fmt.Println("calling kv array")
-			setKVarray(take, envKey, flagKey, def, i+1)
-		}
-		str = parts[i]
-		//fmt.Println(str)
-		idx := strings.Index(str, "=")
-		if idx == -1 {
-			return "", fmt.Errorf(`"=" sign not found for key-value property`)
-		}
-		return str[:idx], nil
-	})
-	set[V](&((*take)[i].Value), envKey, flagKey, def[0].Value, func(str string) (string, error) {
-		parts := strings.Split(str, ",")
-		str = parts[i]
-		//fmt.Println(str)
-		idx := strings.Index(str, "=")
-		if idx == -1 {
-			return "", fmt.Errorf(`"=" sign not found for key-value property`)
+	s := newSetting(take, envKey, flagKey, def, parse.GetParser(take), parse.ToString[T])
+	_, s.isBool = any(take).(*bool)
+	return c.add(s, opts)
+}
+
+// wrap validates the resolved values of s, a copy of them being passed to validator.
+func wrap[T any](s *setting, validator func(*T) error) {
+	if s == nil || validator == nil {
+		return
+	}
+	s.validated = true
+	s.validate = func(v any) error {
+		value := v.(T)
+		if err := validator(&value); err != nil {
+			return s.fieldError(s.formatValue(v), err)
 		}
-		return str[idx+1:], nil
-	})
+		return nil
+	}
 }
 
-// func SetKVs[K Configurable, V Configurable](take *[]*KeyValue[K, V], envKey, flagKey string, def []KeyValue[K, V]) {
-// 	setKVarray(take, envKey, flagKey, def, 0)
-// }
+// Go methods cannot declare type parameters, so the registry-scoped Set* variants are
+// functions taking the *Config first. The package-level Set* functions use Default.
 
-var validators []func() error = []func() error{}
+func SetValidateIn[T any](c *Config, take *T, envKey, flagKey string, def T, validator func(*T) error, opts ...Option) {
+	wrap(set[T](c, take, envKey, flagKey, def, opts...), validator)
+}
 
-func wrap[T Configurable](env, flag string, take *T, validator func(*T) error) {
-	validators = append(validators, func() error {
-		if err := validator(take); err != nil {
-			return fmt.Errorf(`invalid value for env "%s" or flag "%s", %s`, env, flag, err.Error())
-		}
-		return nil
-	})
+func SetEnvValidateIn[T any](c *Config, take *T, envKey string, def T, validator func(*T) error, opts ...Option) {
+	SetValidateIn(c, take, envKey, "", def, validator, opts...)
 }
 
-func wrapKV[K Configurable, V Configurable](env, flag string, take *KeyValue[K, V], keyValidator func(*K) error, valueValidator func(*V) error, keyValueValidator func(*K, *V) error) {
-	validators = append(validators, func() error {
-		if err := keyValidator(&take.Key); err != nil {
-			return fmt.Errorf(`invalid key for env "%s" or flag "%s", %s`, env, flag, err.Error())
-		}
-		return nil
-	})
-	validators = append(validators, func() error {
-		if err := valueValidator(&take.Value); err != nil {
-			return fmt.Errorf(`invalid value for env "%s" or flag "%s", %s`, env, flag, err.Error())
-		}
-		return nil
-	})
-	validators = append(validators, func() error {
-		if err := keyValueValidator(&take.Key, &take.Value); err != nil {
-			return fmt.Errorf(`invalid keyValue for env "%s" or flag "%s", %s`, env, flag, err.Error())
-		}
-		return nil
-	})
+func SetFlagValidateIn[T any](c *Config, take *T, flagKey string, def T, validator func(*T) error, opts ...Option) {
+	SetValidateIn(c, take, "", flagKey, def, validator, opts...)
 }
 
-func SetValidate[T Configurable](take *T, envKey, flagKey string, def T, validator func(*T) error) {
-	wrap(envKey, flagKey, take, validator)
-	set[T](take, envKey, flagKey, def, nil)
+func SetIn[T any](c *Config, take *T, envKey, flagKey string, def T, opts ...Option) {
+	SetValidateIn(c, take, envKey, flagKey, def, nil, opts...)
 }
 
-func SetPairValidate[K Configurable, V Configurable](take *KeyValue[K, V], envKey, flagKey string, def KeyValue[K, V], keyValidator func(*K) error, valueValidator func(*V) error, keyValueValidator func(*K, *V) error) {
-	wrapKV(envKey, flagKey, take, keyValidator, valueValidator, keyValueValidator)
-	*take = def
-	setKVarray(&[]*KeyValue[K, V]{take}, envKey, flagKey, []KeyValue[K, V]{*take}, 0)
+func SetEnvIn[T any](c *Config, take *T, envKey string, def T, opts ...Option) {
+	SetEnvValidateIn(c, take, envKey, def, nil, opts...)
+}
+
+func SetFlagIn[T any](c *Config, take *T, flagKey string, def T, opts ...Option) {
+	SetFlagValidateIn(c, take, flagKey, def, nil, opts...)
 }
 
-func SetEnvValidate[T Configurable](take *T, envKey string, def T, validator func(*T) error) {
-	SetValidate(take, envKey, "", def, validator)
+func SetValidate[T any](take *T, envKey, flagKey string, def T, validator func(*T) error, opts ...Option) {
+	SetValidateIn(Default, take, envKey, flagKey, def, validator, opts...)
 }
 
-func SetFlagValidate[T Configurable](take *T, flagKey string, def T, validator func(*T) error) {
-	SetValidate(take, "", flagKey, def, validator)
+func SetEnvValidate[T any](take *T, envKey string, def T, validator func(*T) error, opts ...Option) {
+	SetEnvValidateIn(Default, take, envKey, def, validator, opts...)
 }
 
-func nopValidate[T Configurable](t *T) error {
-	if t == nil {
-		return errors.New("value is nill")
+func SetFlagValidate[T any](take *T, flagKey string, def T, validator func(*T) error, opts ...Option) {
+	SetFlagValidateIn(Default, take, flagKey, def, validator, opts...)
+}
+
+func Set[T any](take *T, envKey, flagKey string, def T, opts ...Option) {
+	SetIn(Default, take, envKey, flagKey, def, opts...)
+}
+
+func SetEnv[T any](take *T, envKey string, def T, opts ...Option) {
+	SetEnvIn(Default, take, envKey, def, opts...)
+}
+
+func SetFlag[T any](take *T, flagKey string, def T, opts ...Option) {
+	SetFlagIn(Default, take, flagKey, def, opts...)
+}
+
+type sourced struct {
+	source  Source
+	str     string
+	profile string
+}
+
+// resolve stages in every setting its default (or the default of the active profile) and
+// then, in order, the file, profile file, env and flag values found for it.
+func (c *Config) resolve() []error {
+	for _, s := range c.allSettings() {
+		s.value, s.origin = s.defValue(), sourced{SourceDefault, s.def, ""}
 	}
-	return nil
+	values, err := readFiles(c.allFiles())
+	if err == nil {
+		c.dotenv, err = readDotEnvs(c.allDotEnvFiles(), c.lookupEnv)
+	}
+	if err != nil {
+		return []error{err}
+	}
+	errs := c.unknownEnv()
+	profile := ""
+	owner := c.profileOwner()
+	if owner != nil {
+		errs = append(errs, c.resolveSetting(owner.profile, values, nil, "")...)
+		profile = owner.profile.value.(string)
+	}
+	profileValues, err := readFiles(c.allProfileFiles(profile))
+	if err != nil {
+		return append(errs, err)
+	}
+	for _, s := range c.allSettings() {
+		if owner != nil && s == owner.profile {
+			continue
+		}
+		errs = append(errs, c.resolveSetting(s, values, profileValues, profile)...)
+	}
+	return errs
 }
 
-func nopKVValidate[K Configurable, V Configurable](k *K, v *V) error {
-	if k == nil {
-		return errors.New("key is nill")
+func (c *Config) resolveSetting(s *setting, values, profileValues map[string]string, profile string) []error {
+	if def, ok := s.profileDefaults[profile]; ok && profile != "" {
+		s.value = def
+		s.origin = sourced{SourceDefault, s.formatValue(def), profile}
 	}
-	if v == nil {
-		return errors.New("value is nill")
+	raws := []sourced{}
+	if str, ok := values[s.fileKey()]; ok {
+		raws = append(raws, sourced{SourceFile, str, ""})
 	}
-	return nil
+	if str, ok := profileValues[s.fileKey()]; ok {
+		raws = append(raws, sourced{SourceFile, str, profile})
+	}
+	errs := []error{}
+	// aliases come first so that the current keys take precedence
+	for _, key := range append(append([]string{}, s.envAliases...), s.envKey) {
+		if key == "" {
+			continue
+		}
+		str, ok, err := c.lookupEnvOrFile(key)
+		if err != nil {
+			path := ""
+			var pathErr *fs.PathError
+			if errors.As(err, &pathErr) {
+				path = pathErr.Path
+			}
+			errs = append(errs, s.fieldError(path, err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if key != s.envKey {
+			if err := c.deprecated(s, key, "", str); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		raws = append(raws, sourced{SourceEnv, str, ""})
+	}
+	for _, key := range append(append([]string{}, s.flagAliases...), s.flagKey) {
+		flags := c.flagValues[key]
+		if key == "" || len(flags) == 0 {
+			continue
+		}
+		if key != s.flagKey {
+			if err := c.deprecated(s, "", key, flags[0]); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if s.repeatable {
+			// repeated flags add entries instead of replacing the value
+			raws = append(raws, sourced{SourceFlag, strings.Join(flags, ","), ""})
+		} else {
+			for _, str := range flags {
+				raws = append(raws, sourced{SourceFlag, str, ""})
+			}
+		}
+	}
+	for _, raw := range raws {
+		value, err := c.reveal(s, raw.str)
+		var parsed any
+		if err == nil {
+			parsed, err = s.parse(value)
+		}
+		if err != nil {
+			errs = append(errs, s.parseError(raw.str, err))
+			continue
+		}
+		s.value, s.origin = parsed, raw
+	}
+	return errs
 }
 
-func Set[T Configurable](take *T, envKey, flagKey string, def T) {
-	SetValidate(take, envKey, flagKey, def, nopValidate)
+// validate runs the validators of the settings on their staged values.
+func (c *Config) validate() []error {
+	errs := []error{}
+	for _, s := range c.allSettings() {
+		if s.validate != nil {
+			errs = appendFlat(errs, s.validate(s.value))
+		}
+	}
+	return errs
 }
 
-func SetPair[K Configurable, V Configurable](take *KeyValue[K, V], envKey, flagKey string, def KeyValue[K, V]) {
-	SetPairValidate(take, envKey, flagKey, def, nopValidate, nopValidate, nopKVValidate)
+func appendFlat(errs []error, err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return append(errs, joined.Unwrap()...)
+	} else if err != nil {
+		return append(errs, err)
+	}
+	return errs
 }
 
-func SetEnv[T Configurable](take *T, envKey string, def T) {
-	SetEnvValidate(take, envKey, def, nopValidate)
+// commit writes the staged values to the settings and runs the rules, writing back the
+// previous values if a rule fails. It returns the previous values.
+func (c *Config) commit() ([]any, []error) {
+	settings := c.allSettings()
+	old := make([]any, len(settings))
+	origins := make([]sourced, len(settings))
+	for i, s := range settings {
+		old[i], origins[i] = s.get(), sourced{s.source, s.raw, s.profile}
+		s.put(s.value)
+		s.source, s.raw, s.profile = s.origin.source, s.origin.str, s.origin.profile
+	}
+	errs := []error{}
+	for _, rule := range c.allRules() {
+		errs = appendFlat(errs, rule())
+	}
+	if len(errs) > 0 {
+		for i, s := range settings {
+			s.put(old[i])
+			s.source, s.raw, s.profile = origins[i].source, origins[i].str, origins[i].profile
+		}
+	}
+	return old, errs
 }
 
-func SetFlag[T Configurable](take *T, flagKey string, def T) {
-	SetFlagValidate(take, flagKey, def, nopValidate)
+type flagValue struct {
+	c      *Config
+	key    string
+	isBool bool
 }
 
-// func SetEnvKV[K Configurable, V Configurable](take *KeyValue[K, V], envKey string, def KeyValue[K, V]) {
-// 	SetKV(take, envKey, "", def)
-// }
+func (f *flagValue) String() string {
+	return ""
+}
 
-// func SetFlagKV[K Configurable, V Configurable](take *KeyValue[K, V], flagKey string, def KeyValue[K, V]) {
-// 	SetKV(take, "", flagKey, def)
-// }
+func (f *flagValue) Set(s string) error {
+	f.c.flagValues[f.key] = append(f.c.flagValues[f.key], s)
+	return nil
+}
 
-func Read() {
-	if envErr != nil {
-		panic(envErr)
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+func (c *Config) registerFlags() {
+	if c.flags == flag.CommandLine && reflect.ValueOf(flag.Usage).Pointer() == defaultUsage {
+		flag.Usage = c.printUsage
 	}
-	for key, v := range boolFlags {
-		if _, ok := flagFuncs[key]; !ok {
-			flag.BoolVar(v, key, *v, "")
+	for _, s := range c.allSettings() {
+		if s.flagKey != "" && c.flags.Lookup(s.flagKey) == nil {
+			c.flags.Var(&flagValue{c: c, key: s.flagKey, isBool: s.isBool}, s.flagKey, s.description)
+		}
+		for _, alias := range s.flagAliases {
+			if c.flags.Lookup(alias) == nil {
+				c.flags.Var(&flagValue{c: c, key: alias, isBool: s.isBool}, alias, "deprecated, use "+describeKeys(s.envKey, s.flagKey))
+			}
 		}
 	}
-	for key, fn := range flagFuncs {
-		flag.Func(key, "", fn)
+}
+
+// Load resolves every registered setting from files, env and the command line arguments
+// (os.Args[1:]) and runs the validators. Instead of stopping at the first problem it
+// returns all of them joined, each invalid value being reported as a *FieldError, and
+// leaves the settings unchanged.
+func (c *Config) Load() error {
+	return c.LoadArgs(os.Args[1:])
+}
+
+// LoadArgs is like Load but parses the given arguments instead of os.Args[1:].
+func (c *Config) LoadArgs(args []string) error {
+	return c.loadArgs(args, nil)
+}
+
+// loadArgs starts from the flag values already parsed by a parent command.
+func (c *Config) loadArgs(args []string, flagValues map[string][]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	errs := []error{}
+	c.registerFlags()
+	c.flagValues = maps.Clone(flagValues)
+	if c.flagValues == nil {
+		c.flagValues = map[string][]string{}
+	}
+	if err := c.flags.Parse(args); err != nil {
+		errs = append(errs, err)
 	}
-	boolFlags = map[string]*bool{}
-	flagFuncs = map[string]func(string) error{}
-	flag.Parse()
-	for _, validator := range validators {
-		if err := validator(); err != nil {
-			panic(err)
-		}
+	errs = append(errs, c.resolve()...)
+	errs = append(errs, c.validate()...)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if _, errs := c.commit(); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	c.publish()
+	return nil
+}
+
+// Read is like Load but panics if there is any error.
+func (c *Config) Read() {
+	c.ReadArgs(os.Args[1:])
+}
+
+// ReadArgs is like LoadArgs but panics if there is any error.
+func (c *Config) ReadArgs(args []string) {
+	if err := c.LoadArgs(args); err != nil {
+		panic(err)
 	}
-	validators = []func() error{}
+}
+
+func Load() error {
+	return Default.Load()
+}
+
+func Read() {
+	Default.Read()
 }
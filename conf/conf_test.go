@@ -0,0 +1,1029 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enolgor/go-utils/parse"
+	"github.com/enolgor/go-utils/parse/types"
+	"github.com/enolgor/go-utils/sec"
+	"github.com/enolgor/go-utils/validators"
+)
+
+func TestReadFiles(t *testing.T) {
+	dir := t.TempDir()
+	contents := map[string]string{
+		"conf.json": `{"server": {"port": 8080, "hosts": ["a", "b"]}, "log_level": "debug"}`,
+		"conf.yaml": "server:\n  port: 8080\n  hosts: [a, b]\nlog_level: debug\n",
+		"conf.toml": "log_level = \"debug\"\n[server]\nport = 8080\nhosts = [\"a\", \"b\"]\n",
+	}
+	expected := map[string]string{
+		"server.port":  "8080",
+		"server.hosts": "a,b",
+		"log.level":    "debug",
+	}
+	for name, content := range contents {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		values, err := readFiles([]string{path})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		for key, want := range expected {
+			if got := values[key]; got != want {
+				t.Errorf("%s: key %q got %q, wanted %q", name, key, got, want)
+			}
+		}
+	}
+	if _, err := readFiles([]string{filepath.Join(dir, "conf.ini")}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestFileKey(t *testing.T) {
+	type testCase struct {
+		setting *setting
+		output  string
+	}
+	cases := []testCase{
+		{&setting{envKey: "SERVER_PORT", flagKey: "p"}, "server.port"},
+		{&setting{flagKey: "log-level"}, "log.level"},
+	}
+	for _, test := range cases {
+		if got := test.setting.fileKey(); got != test.output {
+			t.Errorf("got %q, wanted %q", got, test.output)
+		}
+	}
+}
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestIndependentConfigs(t *testing.T) {
+	var port1, port2 int
+	var host1, host2 string
+	c1 := New(nil, lookup(map[string]string{"PORT": "1000", "HOST": "one"}))
+	c2 := New(nil, lookup(map[string]string{"HOST": "two"}))
+	SetIn(c1, &port1, "PORT", "p", 8080)
+	SetEnvIn(c1, &host1, "HOST", "localhost")
+	SetIn(c2, &port2, "PORT", "p", 8080)
+	SetEnvIn(c2, &host2, "HOST", "localhost")
+	c1.ReadArgs([]string{})
+	c2.ReadArgs([]string{"-p", "2000"})
+	if port1 != 1000 || host1 != "one" {
+		t.Errorf("got %d %q, wanted 1000 \"one\"", port1, host1)
+	}
+	if port2 != 2000 || host2 != "two" {
+		t.Errorf("got %d %q, wanted 2000 \"two\"", port2, host2)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.yaml")
+	if err := os.WriteFile(path, []byte("a: file\nb: file\nc: file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var a, b, c, d string
+	cfg := New(nil, lookup(map[string]string{"B": "env", "C": "env"}))
+	cfg.FromFile(path)
+	SetIn(cfg, &a, "A", "a", "default")
+	SetIn(cfg, &b, "B", "b", "default")
+	SetIn(cfg, &c, "C", "c", "default")
+	SetIn(cfg, &d, "D", "d", "default")
+	cfg.ReadArgs([]string{"-c", "flag"})
+	if a != "file" || b != "env" || c != "flag" || d != "default" {
+		t.Errorf("got %q %q %q %q, wanted file env flag default", a, b, c, d)
+	}
+}
+
+func TestBind(t *testing.T) {
+	type Database struct {
+		Host string `env:"HOST" flag:"host" default:"localhost"`
+		Port int    `env:"PORT" flag:"port" default:"5432" validate:"min=1024,max=65535"`
+	}
+	type Settings struct {
+		Port     int      `env:"PORT" flag:"p" default:"8080" validate:"min=1000"`
+		Mode     string   `env:"MODE" validate:"oneof=dev prod"`
+		Tags     []string `flag:"tags"`
+		Database Database `env:"DB"`
+		ignored  int
+	}
+	settings := Settings{Mode: "dev"}
+	c := New(nil, lookup(map[string]string{"DB_HOST": "db", "MODE": "prod"}))
+	c.Bind(&settings)
+	c.ReadArgs([]string{"-p", "9000", "-database-port", "6000", "-tags", "a,b"})
+	if settings.Port != 9000 || settings.Mode != "prod" || len(settings.Tags) != 2 {
+		t.Errorf("got %+v", settings)
+	}
+	if settings.Database.Host != "db" || settings.Database.Port != 6000 {
+		t.Errorf("got %+v", settings.Database)
+	}
+
+	var limits struct {
+		Size types.ByteSize `env:"SIZE" validate:"max=1MiB"`
+		Rate types.Rate     `env:"RATE" default:"10/s" validate:"max=100/s"`
+		Mode string         `env:"MODE" validate:"notempty,oneof=dev prod"`
+	}
+	c = New(nil, lookup(map[string]string{"SIZE": "2MiB", "RATE": "1000/s"}))
+	c.Bind(&limits)
+	err := c.LoadArgs(nil)
+	expected := "invalid value \"2MiB\" for env \"SIZE\": size must be at most 1MiB\n" +
+		"invalid value \"1000/s\" for env \"RATE\": rate must be at most 100/s\n" +
+		"invalid value \"\" for env \"MODE\": empty value\nstring should be one of: [dev,prod]"
+	if err == nil || err.Error() != expected {
+		t.Errorf("got %v", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic for a rule of an unsupported type")
+			}
+		}()
+		var unsupported struct {
+			Ratio float64 `env:"RATIO" validate:"min=1"`
+		}
+		c.Bind(&unsupported)
+	}()
+
+	c = New(nil, lookup(map[string]string{"MODE": "test"}))
+	c.Bind(&settings)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected validation panic")
+		}
+	}()
+	c.ReadArgs([]string{})
+}
+
+func TestLoadErrors(t *testing.T) {
+	var port, workers int
+	var mode string
+	c := New(nil, lookup(map[string]string{"PORT": "abc", "WORKERS": "x"}))
+	SetIn(c, &port, "PORT", "p", 8080)
+	SetIn(c, &workers, "WORKERS", "w", 1)
+	SetValidateIn(c, &mode, "MODE", "mode", "test", validators.Strings.OneOf("dev", "prod"))
+	err := c.LoadArgs([]string{"-w", "y"})
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	fieldErrs := []*FieldError{}
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("expected *FieldError, got %T", err)
+		}
+		fieldErrs = append(fieldErrs, fieldErr)
+	}
+	if len(fieldErrs) != 4 {
+		t.Fatalf("expected 4 errors, got %d: %s", len(fieldErrs), err)
+	}
+	if fieldErrs[0].Env != "PORT" || fieldErrs[0].Value != "abc" {
+		t.Errorf("got %+v", fieldErrs[0])
+	}
+	if fieldErrs[2].Flag != "w" || fieldErrs[2].Value != "y" {
+		t.Errorf("got %+v", fieldErrs[2])
+	}
+	if fieldErrs[3].Env != "MODE" || fieldErrs[3].Value != "test" {
+		t.Errorf("got %+v", fieldErrs[3])
+	}
+	// a failed load returns the error and leaves the values as they were
+	var name string
+	var bound struct {
+		Level string `env:"LEVEL" default:"info"`
+	}
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+	c = New(nil, lookup(map[string]string{"PORT": "100"}))
+	SetValidateIn(c, &port, "PORT", "port", 8080, validators.Ints.EqOrGreaterThan(1024))
+	SetIn(c, &name, "NAME", "name", "app")
+	c.Bind(&bound)
+	if err := c.LoadArgs(nil); err == nil || port != 8080 {
+		t.Errorf("got %d %v", port, err)
+	}
+	c.FromFile(missing)
+	if err := c.LoadArgs([]string{"-port", "2000"}); err == nil || port != 8080 || name != "app" || bound.Level != "info" {
+		t.Errorf("got %d %q %q %v", port, name, bound.Level, err)
+	}
+	c = New(nil, lookup(map[string]string{}))
+	SetIn(c, &name, "NAME", "name", "app")
+	c.FromDotEnv(missing)
+	if err := c.LoadArgs(nil); err == nil || name != "app" {
+		t.Errorf("got %q %v", name, err)
+	}
+	c = New(nil, lookup(map[string]string{}))
+	c.Profiles("PROFILE", "profile")
+	c.FromProfileFile("prod", missing)
+	if err := c.LoadArgs([]string{"-profile", "prod"}); err == nil {
+		t.Error("expected missing profile file error")
+	}
+	c = New(nil, lookup(map[string]string{}))
+	c.FromFile(missing)
+	sub := c.Command("serve", "", func([]string) error { return nil })
+	SetIn(sub, &name, "NAME", "name", "app")
+	if err := c.RunArgs([]string{"serve"}); err == nil || name != "app" {
+		t.Errorf("got %q %v", name, err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var port int
+	var host string
+	c := New(nil, lookup(map[string]string{}))
+	SetValidateIn(c, &port, "PORT", "p", 8080, validators.Ints.EqOrGreaterThan(1000), Description("port to listen on"))
+	SetEnvIn(c, &host, "HOST", "localhost")
+	var buf strings.Builder
+	c.Markdown(&buf)
+	expected := "| Flag | Env | Type | Default | Validated | Description |\n" +
+		"| --- | --- | --- | --- | --- | --- |\n" +
+		"| `-p` | `PORT` | `int` | `8080` | yes | port to listen on |\n" +
+		"|  | `HOST` | `string` | `localhost` | no |  |\n"
+	if buf.String() != expected {
+		t.Errorf("got\n%s\nwanted\n%s", buf.String(), expected)
+	}
+	buf.Reset()
+	c.Usage(&buf)
+	if !strings.Contains(buf.String(), "port to listen on") || !strings.Contains(buf.String(), "HOST") {
+		t.Errorf("unexpected usage output:\n%s", buf.String())
+	}
+	// the flag.Usage of the program is kept
+	original := flag.Usage
+	defer func() { flag.Usage = original }()
+	custom := false
+	flag.Usage = func() { custom = true }
+	Default.registerFlags()
+	flag.CommandLine.Usage()
+	if !custom {
+		t.Error("custom flag.Usage replaced")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"level": "info", "limit": 10}`)
+	var level string
+	var limit int
+	c := New(nil, lookup(map[string]string{}))
+	c.FromFile(path)
+	SetIn(c, &level, "LEVEL", "level", "warn")
+	SetValidateIn(c, &limit, "LIMIT", "limit", 1, validators.Ints.EqOrGreaterThan(1))
+	changes := []string{}
+	OnChangeIn(c, &level, func(old, new string) {
+		changes = append(changes, old+"->"+new)
+	})
+	if err := c.LoadArgs([]string{"-limit", "20"}); err != nil {
+		t.Fatal(err)
+	}
+	write(`{"level": "debug", "limit": 5}`)
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if level != "debug" || limit != 20 || len(changes) != 1 || changes[0] != "info->debug" {
+		t.Errorf("got %q %d %v", level, limit, changes)
+	}
+	write(`{"level": "error", "limit": "x"}`)
+	c.flagValues = map[string][]string{}
+	if err := c.Reload(); err == nil {
+		t.Fatal("expected error")
+	}
+	if level != "debug" || limit != 20 || len(changes) != 1 {
+		t.Errorf("invalid reload changed values: %q %d %v", level, limit, changes)
+	}
+	// the values being validated are not visible until the reload succeeds
+	seen := []string{}
+	var workers int
+	SetValidateIn(c, &workers, "WORKERS", "workers", 1, func(*int) error {
+		seen = append(seen, level)
+		return nil
+	})
+	write(`{"level": "trace", "limit": 0}`)
+	if err := c.Reload(); err == nil || level != "debug" || len(seen) != 1 || seen[0] != "debug" {
+		t.Errorf("got %v %q %v", err, level, seen)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	crypto := sec.AES([]byte("0123456789abcdef"))
+	encrypted, err := crypto.Encrypt([]byte("s3cr3t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var password, token, pin string
+	env := map[string]string{"PASSWORD": "enc:" + encrypted, "TOKEN_FILE": path, "PIN": "123456"}
+	c := New(nil, lookup(env))
+	c.SetCrypto(crypto)
+	SetEnvIn(c, &password, "PASSWORD", "", Secret)
+	SetEnvIn(c, &token, "TOKEN", "", Secret)
+	SetEnvValidateIn(c, &pin, "PIN", "0000", validators.Strings.Len(6), Secret)
+	if err := c.LoadArgs([]string{}); err != nil || password != "s3cr3t" || token != "from-file" {
+		t.Errorf("got %q %q %v", password, token, err)
+	}
+	env["PIN"] = "1234"
+	err = c.LoadArgs([]string{})
+	if err == nil || strings.Contains(err.Error(), "1234") || !strings.Contains(err.Error(), redacted) {
+		t.Errorf("secret not redacted: %v", err)
+	}
+	var buf strings.Builder
+	c.Usage(&buf)
+	if strings.Contains(buf.String(), "0000") {
+		t.Errorf("secret default not redacted:\n%s", buf.String())
+	}
+	// parse errors quote the value, so they are redacted too
+	var code int
+	missing := filepath.Join(t.TempDir(), "missing")
+	c = New(nil, lookup(map[string]string{"CODE": "hunter2", "KEY_FILE": missing}))
+	SetEnvIn(c, &code, "CODE", 0, Secret)
+	SetEnvIn(c, &token, "KEY", "", Secret)
+	err = c.LoadArgs(nil)
+	var fieldErr *FieldError
+	if err == nil || strings.Contains(err.Error(), "hunter2") || !errors.As(err, &fieldErr) || fieldErr.Env != "CODE" {
+		t.Errorf("secret not redacted: %v", err)
+	}
+	if !strings.Contains(err.Error(), `for env "KEY": open `+missing) || strings.Contains(err.Error(), "KEY_FILE") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestKeyValues(t *testing.T) {
+	var pair KeyValue[string, int]
+	var kvs []KeyValue[string, string]
+	var limits map[string]int
+	c := New(nil, lookup(map[string]string{"PAIR": "a=1", "KVS": `b=2,a=x\,y,c=k\=v`}))
+	SetPairIn(c, &pair, "PAIR", "pair", KeyValue[string, int]{"z", 0})
+	SetKVsIn(c, &kvs, "KVS", "kvs", nil)
+	SetMapValidateIn(c, &limits, "LIMITS", "limit", map[string]int{"default": 1}, validators.Strings.NotEmpty, validators.Ints.EqOrGreaterThan(1))
+	if err := c.LoadArgs([]string{"-limit", "b=2", "-limit", "a=1,c=3"}); err != nil {
+		t.Fatal(err)
+	}
+	if pair.Key != "a" || pair.Value != 1 {
+		t.Errorf("got %+v", pair)
+	}
+	expected := []KeyValue[string, string]{{"b", "2"}, {"a", "x,y"}, {"c", "k=v"}}
+	if len(kvs) != len(expected) {
+		t.Fatalf("got %+v", kvs)
+	}
+	for i := range expected {
+		if kvs[i] != expected[i] {
+			t.Errorf("got %+v, wanted %+v", kvs[i], expected[i])
+		}
+	}
+	if got := formatKVs(kvs); got != `b=2,a=x\,y,c=k\=v` {
+		t.Errorf("got %q", got)
+	}
+	if got := formatMap(limits); got != "a=1,b=2,c=3" {
+		t.Errorf("got %q", got)
+	}
+	c = New(nil, lookup(map[string]string{}))
+	SetMapValidateIn(c, &limits, "LIMITS", "limit", nil, nil, validators.Ints.EqOrGreaterThan(1))
+	err := c.LoadArgs([]string{"-limit", "a=0,b=5,c=-1"})
+	if err == nil || len(err.(interface{ Unwrap() []error }).Unwrap()) != 2 {
+		t.Errorf("expected 2 errors, got %v", err)
+	}
+	// keys and values may be of any type supported by parse.Codec
+	var levels map[string]logLevel
+	var hosts []KeyValue[logLevel, hostPort]
+	c = New(nil, lookup(map[string]string{"LEVELS": "db=debug,http=error", "HOSTS": "info=localhost:80"}))
+	SetMapIn(c, &levels, "LEVELS", "levels", nil)
+	SetKVsIn(c, &hosts, "HOSTS", "hosts", nil)
+	if err := c.LoadArgs(nil); err != nil {
+		t.Fatal(err)
+	}
+	if levels["http"] != 2 || len(hosts) != 1 || hosts[0].Key != 1 || formatMap(levels) != "db=debug,http=error" {
+		t.Errorf("got %v %v", levels, hosts)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for an unsupported value type")
+		}
+	}()
+	var unsupported map[string]struct{}
+	SetMapIn(c, &unsupported, "UNSUPPORTED", "unsupported", nil)
+}
+
+func TestDump(t *testing.T) {
+	var host, password string
+	var port, workers int
+	var debug bool
+	c := New(nil, lookup(map[string]string{"PORT": "9090", "PASSWORD": "hunter2"}))
+	SetIn(c, &host, "HOST", "host", "localhost")
+	SetIn(c, &port, "PORT", "port", 8080)
+	SetIn(c, &workers, "WORKERS", "workers", 1)
+	SetIn(c, &debug, "DEBUG", "debug", false)
+	SetIn(c, &password, "PASSWORD", "password", "", Secret)
+	if err := c.LoadArgs([]string{"-workers", "04", "-debug"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Entry{
+		{Env: "HOST", Flag: "host", Type: "string", Value: "localhost", Source: SourceDefault, Raw: "localhost"},
+		{Env: "PORT", Flag: "port", Type: "int", Value: "9090", Source: SourceEnv, Raw: "9090"},
+		{Env: "WORKERS", Flag: "workers", Type: "int", Value: "4", Source: SourceFlag, Raw: "04"},
+		{Env: "DEBUG", Flag: "debug", Type: "bool", Value: "true", Source: SourceFlag, Raw: "true"},
+		{Env: "PASSWORD", Flag: "password", Type: "string", Value: redacted, Source: SourceEnv, Raw: redacted},
+	}
+	entries := c.Dump()
+	if len(entries) != len(expected) {
+		t.Fatalf("got %+v", entries)
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("got %+v, wanted %+v", entries[i], expected[i])
+		}
+	}
+	rec := httptest.NewRecorder()
+	c.DumpHandler()(rec, httptest.NewRequest("GET", "/config", nil))
+	if rec.Header().Get("Content-Type") != "application/json" || strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("got %s", rec.Body.String())
+	}
+}
+
+func TestCommands(t *testing.T) {
+	var verbose bool
+	var port int
+	var steps int
+	c := New(nil, lookup(map[string]string{"PORT": "9090"}))
+	SetIn(c, &verbose, "VERBOSE", "v", false)
+	ran := ""
+	var rest []string
+	serve := c.Command("serve", "start the server", func(args []string) error {
+		ran, rest = "serve", args
+		return nil
+	})
+	SetValidateIn(serve, &port, "PORT", "port", 8080, validators.Ints.EqOrGreaterThan(1024))
+	migrate := c.Command("migrate", "run the migrations", func(args []string) error {
+		ran, rest = "migrate", args
+		return nil
+	})
+	SetIn(migrate, &steps, "STEPS", "steps", 0)
+	if err := c.RunArgs([]string{"-v", "serve", "extra"}); err != nil {
+		t.Fatal(err)
+	}
+	if ran != "serve" || !verbose || port != 9090 || len(rest) != 1 || rest[0] != "extra" {
+		t.Errorf("got %s %v %d %v", ran, verbose, port, rest)
+	}
+	if err := c.RunArgs([]string{"migrate", "-steps", "3", "-v=false"}); err != nil {
+		t.Fatal(err)
+	}
+	if ran != "migrate" || verbose || steps != 3 {
+		t.Errorf("got %s %v %d", ran, verbose, steps)
+	}
+	ran = ""
+	var fe *FieldError
+	if err := c.RunArgs([]string{"serve", "-port", "80"}); !errors.As(err, &fe) || fe.Flag != "port" || ran != "" {
+		t.Errorf("expected port error, got %v", err)
+	}
+	c.flags.SetOutput(io.Discard)
+	migrate.flags.SetOutput(io.Discard)
+	if err := c.RunArgs([]string{"migrate", "-port", "9000"}); err == nil {
+		t.Error("expected unknown flag error")
+	}
+	if err := c.RunArgs([]string{"keygen"}); err == nil {
+		t.Error("expected unknown command error")
+	}
+}
+
+func TestDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# comment\n" +
+		"export HOST=example.com # inline comment\n" +
+		"PORT=8080\n" +
+		"URL=http://${HOST}:$PORT/${BASE:-api}\n" +
+		"LITERAL='${HOST}\\n'\n" +
+		"KEY=\"-----BEGIN-----\n" +
+		"abc\\\"def\n" +
+		"-----END-----\"\n" +
+		"USER=${USER:-nobody}\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var host, url, literal, key, user string
+	var port int
+	c := New(nil, lookup(map[string]string{"PORT": "9090", "USER": "root"}))
+	c.FromDotEnv(path)
+	SetIn(c, &host, "HOST", "host", "")
+	SetIn(c, &port, "PORT", "port", 0)
+	SetIn(c, &url, "URL", "url", "")
+	SetIn(c, &literal, "LITERAL", "literal", "")
+	SetIn(c, &key, "KEY", "key", "")
+	SetIn(c, &user, "USER", "user", "")
+	if err := c.LoadArgs([]string{"-host", "flag.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if host != "flag.com" || port != 9090 || url != "http://example.com:9090/api" || user != "root" {
+		t.Errorf("got %s %d %s %s", host, port, url, user)
+	}
+	if literal != `${HOST}\n` || key != "-----BEGIN-----\nabc\"def\n-----END-----" {
+		t.Errorf("got %q %q", literal, key)
+	}
+	values := map[string]string{}
+	if err := parseDotEnv("A=\"unterminated\nB=1\n", lookup(nil), values); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected unterminated error, got %v", err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	base, prod := filepath.Join(dir, "base.yaml"), filepath.Join(dir, "prod.yaml")
+	os.WriteFile(base, []byte("host: base.com\nworkers: 2\n"), 0600)
+	os.WriteFile(prod, []byte("host: prod.com\n"), 0600)
+	var host string
+	var port, workers int
+	var debug bool
+	c := New(nil, lookup(map[string]string{"APP_PROFILE": "dev"}))
+	c.Profiles("APP_PROFILE", "profile")
+	c.FromFile(base)
+	c.FromProfileFile("prod", prod)
+	SetIn(c, &host, "HOST", "host", "localhost")
+	SetIn(c, &port, "PORT", "port", 8080, ProfileDefault("prod", 443))
+	SetIn(c, &workers, "WORKERS", "workers", 1, ProfileDefault("prod", 8))
+	SetIn(c, &debug, "DEBUG", "debug", false, ProfileDefault("dev", true))
+	if err := c.LoadArgs(nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.Profile() != "dev" || host != "base.com" || port != 8080 || workers != 2 || !debug {
+		t.Errorf("got %s %s %d %d %v", c.Profile(), host, port, workers, debug)
+	}
+	if err := c.LoadArgs([]string{"-profile", "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Profile() != "prod" || host != "prod.com" || port != 443 || workers != 2 || debug {
+		t.Errorf("got %s %s %d %d %v", c.Profile(), host, port, workers, debug)
+	}
+	profiles := map[string]string{}
+	for _, entry := range c.Dump() {
+		profiles[entry.Env] = string(entry.Source) + ":" + entry.Profile
+	}
+	expected := map[string]string{"APP_PROFILE": "flag:", "HOST": "file:prod", "PORT": "default:prod", "WORKERS": "file:", "DEBUG": "default:"}
+	for key, value := range expected {
+		if profiles[key] != value {
+			t.Errorf("%s: got %s, wanted %s", key, profiles[key], value)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a default of the wrong type")
+		}
+	}()
+	SetIn(c, &port, "PORT2", "port2", 0, ProfileDefault("prod", "443"))
+}
+
+func TestRule(t *testing.T) {
+	var tls bool
+	var cert string
+	var min, max int
+	c := New(nil, lookup(map[string]string{"TLS": "true", "MIN": "10"}))
+	SetIn(c, &tls, "TLS", "tls", false)
+	SetIn(c, &cert, "TLS_CERT", "tls-cert", "")
+	SetIn(c, &min, "MIN", "min", 0)
+	SetIn(c, &max, "MAX", "max", 5)
+	c.Rule(func() error {
+		if tls && cert == "" {
+			return errors.New("TLS_CERT is required when TLS is enabled")
+		}
+		return nil
+	}, &tls, &cert)
+	c.Rule(func() error {
+		if min >= max {
+			return errors.New("MIN must be lower than MAX")
+		}
+		return nil
+	}, &min, &max)
+	err := c.LoadArgs(nil)
+	expected := []string{
+		`invalid combination of values "true" for env "TLS" or flag "tls", "" for env "TLS_CERT" or flag "tls-cert": TLS_CERT is required when TLS is enabled`,
+		`invalid combination of values "10" for env "MIN" or flag "min", "5" for env "MAX" or flag "max": MIN must be lower than MAX`,
+	}
+	if err == nil || err.Error() != strings.Join(expected, "\n") {
+		t.Fatalf("got %v", err)
+	}
+	var re *RuleError
+	if !errors.As(err, &re) || len(re.Fields) != 2 || re.Fields[1].Flag != "tls-cert" {
+		t.Errorf("got %+v", re)
+	}
+	if err := c.LoadArgs([]string{"-tls-cert", "cert.pem", "-max", "20"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var level, token string
+	var port int
+	var ratios []float64
+	var limits map[string]int
+	var timeout time.Duration
+	c := New(nil, lookup(map[string]string{"LEVEL": "debug"}))
+	SetValidateIn(c, &level, "LEVEL", "level", "info", validators.All(validators.Strings.NotEmpty, validators.Strings.OneOf("debug", "info")), Description("log level"), Enum("debug", "info"))
+	SetValidateIn(c, &port, "PORT", "port", 0, validators.Ints.EqOrGreaterThan(1))
+	SetIn(c, &ratios, "", "ratios", []float64{0.5, 1})
+	SetMapIn(c, &limits, "LIMITS", "limits", map[string]int{"a": 1})
+	SetIn(c, &timeout, "TIMEOUT", "timeout", time.Second)
+	SetIn(c, &token, "TOKEN", "token", "secret", Secret)
+	if err := c.LoadArgs([]string{"-port", "80"}); err != nil {
+		t.Fatal(err)
+	}
+	// Schema only reads the settings, so it can run while they are read elsewhere
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			_ = level + strconv.Itoa(port)
+		}
+		done <- true
+	}()
+	var sb strings.Builder
+	if err := c.Schema(&sb); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	var schema struct {
+		Properties map[string]map[string]any
+		Required   []string
+	}
+	if err := json.Unmarshal([]byte(sb.String()), &schema); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"LEVEL":   `{"default":"info","description":"log level","enum":["debug","info"],"type":"string"}`,
+		"PORT":    `{"default":0,"type":"integer"}`,
+		"ratios":  `{"default":[0.5,1],"items":{"type":"number"},"type":"array"}`,
+		"LIMITS":  `{"additionalProperties":{"type":"integer"},"default":{"a":1},"type":"object"}`,
+		"TIMEOUT": `{"default":"1s","type":"string"}`,
+		"TOKEN":   `{"type":"string","writeOnly":true}`,
+	}
+	for name, value := range expected {
+		property, _ := json.Marshal(schema.Properties[name])
+		if string(property) != value {
+			t.Errorf("%s: got %s, wanted %s", name, property, value)
+		}
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "PORT" || level != "debug" {
+		t.Errorf("got %v %s", schema.Required, level)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	var addr string
+	var port int
+	var warnings strings.Builder
+	c := New(nil, lookup(map[string]string{"HOST": "old.com", "OLD_PORT": "1", "PORT": "2"}))
+	c.SetWarnings(&warnings)
+	SetIn(c, &addr, "BIND_ADDR", "bind-addr", "localhost", Alias("HOST", "host"))
+	SetIn(c, &port, "PORT", "port", 0, Alias("OLD_PORT", ""))
+	if err := c.LoadArgs(nil); err != nil {
+		t.Fatal(err)
+	}
+	if addr != "old.com" || port != 2 {
+		t.Errorf("got %s %d", addr, port)
+	}
+	if err := c.LoadArgs([]string{"-host", "flag.com"}); err != nil || addr != "flag.com" {
+		t.Errorf("got %s %v", addr, err)
+	}
+	expected := "conf: env \"HOST\" is deprecated, use env \"BIND_ADDR\" or flag \"bind-addr\"\n" +
+		"conf: env \"OLD_PORT\" is deprecated, use env \"PORT\" or flag \"port\"\n"
+	if !strings.HasPrefix(warnings.String(), expected) || !strings.Contains(warnings.String(), `flag "host" is deprecated`) {
+		t.Errorf("got %s", warnings.String())
+	}
+	c.SetStrict(true)
+	err := c.LoadArgs(nil)
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Env != "HOST" || len(err.(interface{ Unwrap() []error }).Unwrap()) != 2 {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	env := map[string]string{"WORKERS": "2"}
+	c := New(nil, lookup(env))
+	workers := NewValueIn(c, "WORKERS", "workers", 1, validators.Ints.EqOrGreaterThan(1))
+	name := NewValueIn[string](c, "", "", "fixed", nil)
+	if workers.Get() != 1 || name.Get() != "fixed" {
+		t.Errorf("got %d %s", workers.Get(), name.Get())
+	}
+	if err := c.LoadArgs(nil); err != nil {
+		t.Fatal(err)
+	}
+	var changes []int
+	workers.Watch(func(old, new int) {
+		if workers.Get() != new {
+			t.Errorf("got %d, wanted %d", workers.Get(), new)
+		}
+		changes = append(changes, old, new)
+	})
+	env["WORKERS"] = "0"
+	if err := c.Reload(); err == nil || workers.Get() != 2 {
+		t.Errorf("got %d %v", workers.Get(), err)
+	}
+	env["WORKERS"] = "8"
+	if err := c.Reload(); err != nil || workers.Get() != 8 {
+		t.Errorf("got %d %v", workers.Get(), err)
+	}
+	if len(changes) != 2 || changes[0] != 2 || changes[1] != 8 {
+		t.Errorf("got %v", changes)
+	}
+	// a failed load does not publish the rejected value
+	c = New(nil, lookup(map[string]string{"W": "0"}))
+	invalid := NewValueIn(c, "W", "w", 1, validators.Ints.EqOrGreaterThan(1))
+	if err := c.LoadArgs(nil); err == nil || invalid.Get() != 1 {
+		t.Errorf("got %d %v", invalid.Get(), err)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	var port int
+	var warnings strings.Builder
+	env := map[string]string{"MYAPP_PROT": "9000", "MYAPP_PORT_FILE": "", "MYAPP_XYZ": "1", "OTHER": "1"}
+	c := New(nil, lookup(env))
+	c.SetEnviron(func() []string {
+		environ := []string{}
+		for key, value := range env {
+			environ = append(environ, key+"="+value)
+		}
+		return environ
+	})
+	c.SetWarnings(&warnings)
+	c.SetPrefix("MYAPP_")
+	SetIn(c, &port, "MYAPP_PORT", "port", 8080)
+	if err := c.LoadArgs(nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := "conf: unknown env \"MYAPP_PROT\", did you mean \"MYAPP_PORT\"?\nconf: unknown env \"MYAPP_XYZ\"\n"
+	if warnings.String() != expected {
+		t.Errorf("got %q", warnings.String())
+	}
+	c.SetStrict(true)
+	if err := c.LoadArgs(nil); err != nil {
+		t.Errorf("strict aliases failed on unknown env: %v", err)
+	}
+	c.SetStrict(false)
+	c.SetPrefixStrict(true)
+	if err := c.LoadArgs(nil); err == nil || !strings.Contains(err.Error(), `did you mean "MYAPP_PORT"?`) {
+		t.Errorf("got %v", err)
+	}
+	// without SetEnviron only the env of dotenv files is scanned
+	c = New(nil, lookup(env))
+	c.SetPrefix("MYAPP_")
+	c.SetPrefixStrict(true)
+	SetIn(c, &port, "MYAPP_PORT", "port", 8080)
+	if err := c.LoadArgs(nil); err != nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+type logLevel int
+
+var logLevels = []string{"debug", "info", "error"}
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+	for i := range logLevels {
+		if logLevels[i] == string(text) {
+			*l = logLevel(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level %s", text)
+}
+
+func (l logLevel) MarshalText() ([]byte, error) {
+	return []byte(logLevels[l]), nil
+}
+
+type hostPort struct {
+	Host string
+	Port int
+}
+
+func (h *hostPort) UnmarshalText(text []byte) error {
+	host, port, ok := strings.Cut(string(text), ":")
+	if !ok {
+		return errors.New("missing port")
+	}
+	h.Host = host
+	return parse.Parse(&h.Port, port)
+}
+
+func (h hostPort) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%s:%d", h.Host, h.Port)), nil
+}
+
+func TestCustomTypes(t *testing.T) {
+	var level logLevel
+	var addr hostPort
+	var peers []hostPort
+	var bound struct {
+		Level logLevel `env:"BOUND_LEVEL" default:"error"`
+		Addr  hostPort `env:"BOUND_ADDR"`
+	}
+	c := New(nil, lookup(map[string]string{"LEVEL": "debug", "PEERS": "a:1,b:2", "BOUND_ADDR": "c:3"}))
+	SetValidateIn(c, &level, "LEVEL", "level", logLevel(1), validators.All(func(l *logLevel) error {
+		if *l == 0 {
+			return errors.New("debug not allowed")
+		}
+		return nil
+	}))
+	SetIn(c, &addr, "ADDR", "addr", hostPort{"localhost", 80})
+	SetIn(c, &peers, "PEERS", "peers", nil)
+	c.Bind(&bound)
+	err := c.LoadArgs(nil)
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Value != "debug" {
+		t.Errorf("got %v", err)
+	}
+	if err := c.LoadArgs([]string{"-level", "error", "-addr", "example.com:8080"}); err != nil {
+		t.Fatal(err)
+	}
+	if level != 2 || addr != (hostPort{"example.com", 8080}) || len(peers) != 2 || peers[1] != (hostPort{"b", 2}) {
+		t.Errorf("got %v %v %v", level, addr, peers)
+	}
+	if bound.Level != 2 || bound.Addr != (hostPort{"c", 3}) {
+		t.Errorf("got %+v", bound)
+	}
+	if parse.ToString(peers) != "a:1,b:2" || parse.ToString(level) != "error" {
+		t.Errorf("got %s %s", parse.ToString(peers), parse.ToString(level))
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for an unsupported type")
+		}
+	}()
+	var unsupported struct{ A int }
+	SetIn(c, &unsupported, "UNSUPPORTED", "", unsupported)
+}
+
+func TestNetworkTypes(t *testing.T) {
+	var endpoint *url.URL
+	var allowed []netip.Prefix
+	var listen netip.AddrPort
+	var ip netip.Addr
+	var pattern *regexp.Regexp
+	var mode os.FileMode
+	var admins []mail.Address
+	c := New(nil, lookup(map[string]string{
+		"ENDPOINT": "https://example.com/api?x=1",
+		"ALLOWED":  "10.0.0.0/8, fd00::/8",
+		"LISTEN":   "[::1]:8080",
+		"IP":       "192.168.1.1",
+		"PATTERN":  `^/api/v\d+/`,
+		"MODE":     "0640",
+		"ADMINS":   `Jane Doe <jane@example.com>, ops@example.com`,
+	}))
+	SetIn(c, &endpoint, "ENDPOINT", "endpoint", nil)
+	SetIn(c, &allowed, "ALLOWED", "allowed", nil)
+	SetIn(c, &listen, "LISTEN", "listen", netip.AddrPort{})
+	SetIn(c, &ip, "IP", "ip", netip.Addr{})
+	SetIn(c, &pattern, "PATTERN", "pattern", nil)
+	SetIn(c, &mode, "MODE", "mode", 0600)
+	SetIn(c, &admins, "ADMINS", "admins", nil)
+	if err := c.LoadArgs(nil); err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.Host != "example.com" || !allowed[1].Contains(netip.MustParseAddr("fd00::1")) || listen.Port() != 8080 || !ip.Is4() {
+		t.Errorf("got %v %v %v %v", endpoint, allowed, listen, ip)
+	}
+	if !pattern.MatchString("/api/v2/users") || mode != 0640 || admins[0].Name != "Jane Doe" || admins[1].Address != "ops@example.com" {
+		t.Errorf("got %v %v %v", pattern, mode, admins)
+	}
+	if dump := c.Dump(); dump[1].Value != "10.0.0.0/8,fd00::/8" || dump[6].Value != `"""Jane Doe"" <jane@example.com>",<ops@example.com>` {
+		t.Errorf("got %+v", dump)
+	}
+	// zero values have an empty default instead of "invalid IP"
+	if c.settings[2].def != "" || c.settings[3].def != "" {
+		t.Errorf("got %q %q", c.settings[2].def, c.settings[3].def)
+	}
+	if err := c.LoadArgs([]string{"-ip", "999.1.1.1"}); err == nil {
+		t.Error("expected invalid ip error")
+	}
+}
+
+func TestUnits(t *testing.T) {
+	var limit types.ByteSize
+	var rates []types.Rate
+	var rate types.Rate
+	c := New(nil, lookup(map[string]string{"LIMIT": "2GB", "RATES": "100/s,5000/h,10/30s"}))
+	SetValidateIn(c, &limit, "LIMIT", "limit", 1<<20, validators.ByteSizes.AtMost(1<<30))
+	SetIn(c, &rates, "RATES", "rates", nil)
+	SetValidateIn(c, &rate, "RATE", "rate", types.Rate{Count: 10, Per: time.Second}, validators.Rates.AtMost(types.Rate{Count: 100, Per: time.Second}))
+	err := c.LoadArgs([]string{"-rate", "7000/m"})
+	if err == nil || !strings.Contains(err.Error(), "size must be at most 1GiB") || !strings.Contains(err.Error(), "rate must be at most 100/s") {
+		t.Errorf("got %v", err)
+	}
+	if limit != 1<<20 || rates != nil {
+		t.Errorf("values written by a failed load: %v %v", limit, rates)
+	}
+	if err := c.LoadArgs([]string{"-limit", "1GiB"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 3 || rates[1] != (types.Rate{Count: 5000, Per: time.Hour}) || c.Dump()[1].Value != "100/s,5000/h,10/30s" {
+		t.Errorf("got %v", rates)
+	}
+}
+
+func TestTimes(t *testing.T) {
+	var since time.Time
+	c := New(nil, lookup(map[string]string{"SINCE": "1710064800"}))
+	SetIn(c, &since, "SINCE", "since", time.Time{})
+	if err := c.LoadArgs(nil); err != nil || !since.Equal(time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v %v", since, err)
+	}
+}
+
+func TestLists(t *testing.T) {
+	var names []string
+	var times []time.Time
+	var matrix [][]int
+	c := New(nil, lookup(map[string]string{"NAMES": `"Doe, Jane",Smith`, "MATRIX": "1,2;3,4, 5"}))
+	SetIn(c, &names, "NAMES", "names", nil)
+	SetIn(c, &times, "TIMES", "times", nil)
+	SetIn(c, &matrix, "MATRIX", "matrix", nil)
+	if err := c.LoadArgs([]string{"-times", `"Sun, 10 Mar 2024 10:00:00 +0000";2024-03-11`}); err == nil {
+		t.Error("expected error for a list with the wrong separator")
+	}
+	if err := c.LoadArgs([]string{"-times", `"Sun, 10 Mar 2024 10:00:00 +0000",2024-03-11`}); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "Doe, Jane" || len(times) != 2 || times[1].Day() != 11 || len(matrix) != 2 || matrix[1][2] != 5 {
+		t.Errorf("got %q %v %v", names, times, matrix)
+	}
+	if dump := c.Dump(); dump[0].Value != `"Doe, Jane",Smith` || dump[2].Value != "1,2;3,4,5" {
+		t.Errorf("got %+v", dump)
+	}
+}
+
+func TestDurations(t *testing.T) {
+	var retention time.Duration
+	c := New(nil, lookup(map[string]string{"RETENTION": "2h30m"}))
+	SetIn(c, &retention, "RETENTION", "retention", 48*time.Hour)
+	if err := c.LoadArgs(nil); err != nil {
+		t.Fatal(err)
+	}
+	if entry := c.Dump()[0]; retention != 150*time.Minute || entry.Value != "2h30m" || c.settings[0].def != "48h" {
+		t.Errorf("got %v %+v", retention, entry)
+	}
+}
+
+// severity is registered as a parse.Enum once, as the package that declares it would.
+type severity int
+
+var _ = parse.NewEnum([]parse.EnumMember[severity]{
+	{Name: "debug", Value: -4}, {Name: "info", Value: 0}, {Name: "warn", Value: 4}, {Name: "warning", Value: 4}, {Name: "error", Value: 8},
+})
+
+func TestEnum(t *testing.T) {
+	var level severity
+	var muted []severity
+	c := New(nil, lookup(map[string]string{"MUTED": "Debug,INFO"}))
+	SetIn(c, &level, "LEVEL", "level", severity(0))
+	SetIn(c, &muted, "MUTED", "muted", nil)
+	if err := c.LoadArgs([]string{"-level", "Error"}); err != nil {
+		t.Fatal(err)
+	}
+	if level != 8 || len(muted) != 2 || c.Dump()[1].Value != "debug,info" {
+		t.Errorf("got %v %v", level, muted)
+	}
+	var usage strings.Builder
+	c.Usage(&usage)
+	if !strings.Contains(usage.String(), "conf.severity (debug|info|warn|error)") {
+		t.Errorf("got %s", usage.String())
+	}
+	var schema strings.Builder
+	if err := c.Schema(&schema); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Properties map[string]struct {
+			Default any
+			Enum    []string
+			Items   struct{ Enum []string }
+		}
+	}
+	if err := json.Unmarshal([]byte(schema.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if p := doc.Properties["LEVEL"]; p.Default != "info" || len(p.Enum) != 4 || len(doc.Properties["MUTED"].Items.Enum) != 4 {
+		t.Errorf("got %s", schema.String())
+	}
+}
@@ -0,0 +1,184 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FromDotEnv adds a dotenv file whose variables are used for the env keys not set in the
+// real environment. Lines are KEY=value, optionally prefixed with export, and # starts a
+// comment. Single quoted values are literal, double quoted values may span several lines
+// and support \n, \t and \" escapes. Unquoted and double quoted values expand $VAR,
+// ${VAR} and ${VAR:-default} from the real environment or the variables defined before.
+func (c *Config) FromDotEnv(path string) {
+	c.dotenvFiles = append(c.dotenvFiles, path)
+}
+
+func FromDotEnv(path string) {
+	Default.FromDotEnv(path)
+}
+
+func (c *Config) getEnv(key string) (string, bool) {
+	if str, ok := c.lookupEnv(key); ok {
+		return str, true
+	}
+	str, ok := c.dotenv[key]
+	return str, ok
+}
+
+func readDotEnvs(paths []string, lookupEnv func(string) (string, bool)) (map[string]string, error) {
+	values := map[string]string{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err == nil {
+			err = parseDotEnv(string(content), lookupEnv, values)
+		}
+		if err != nil {
+			return nil, fmt.Errorf(`cannot read dotenv file "%s": %s`, path, err.Error())
+		}
+	}
+	return values, nil
+}
+
+func parseDotEnv(src string, lookupEnv func(string) (string, bool), values map[string]string) error {
+	lookup := func(key string) (string, bool) {
+		if str, ok := lookupEnv(key); ok {
+			return str, true
+		}
+		str, ok := values[key]
+		return str, ok
+	}
+	line := 0
+	for src != "" {
+		var stmt string
+		stmt, src = cutLine(src)
+		line++
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" || stmt[0] == '#' {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(stmt, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			stmt = strings.TrimSpace(rest)
+		}
+		key, value, ok := strings.Cut(stmt, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isEnvName(key) {
+			return fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		value = strings.TrimSpace(value)
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			values[key] = expand(value, lookup, false)
+			continue
+		}
+		quote, start := value[0], line
+		end := closingQuote(value, quote)
+		for end < 0 {
+			if src == "" {
+				return fmt.Errorf("line %d: unterminated quoted value", start)
+			}
+			var next string
+			next, src = cutLine(src)
+			line++
+			value += "\n" + next
+			end = closingQuote(value, quote)
+		}
+		if rest := strings.TrimSpace(value[end+1:]); rest != "" && rest[0] != '#' {
+			return fmt.Errorf("line %d: unexpected characters after quoted value", line)
+		}
+		if quote == '\'' {
+			values[key] = value[1:end]
+		} else {
+			values[key] = expand(value[1:end], lookup, true)
+		}
+	}
+	return nil
+}
+
+func cutLine(src string) (string, string) {
+	line, rest, _ := strings.Cut(src, "\n")
+	return strings.TrimSuffix(line, "\r"), rest
+}
+
+// closingQuote returns the index of the quote closing the value starting with it or -1.
+func closingQuote(value string, quote byte) int {
+	for i := 1; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func isEnvName(str string) bool {
+	if str == "" || (str[0] >= '0' && str[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(str); i++ {
+		if !isEnvNameChar(str[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEnvNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func expand(str string, lookup func(string) (string, bool), escapes bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(str); i++ {
+		switch {
+		case escapes && str[i] == '\\' && i+1 < len(str):
+			i++
+			switch str[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(str[i])
+			}
+		case str[i] == '$' && i+1 < len(str) && str[i+1] == '{':
+			end := strings.IndexByte(str[i:], '}')
+			if end < 0 {
+				sb.WriteString(str[i:])
+				return sb.String()
+			}
+			name, def, hasDef := strings.Cut(str[i+2:i+end], ":-")
+			value, ok := lookup(name)
+			if hasDef && (!ok || value == "") {
+				value = def
+			}
+			sb.WriteString(value)
+			i += end
+		case str[i] == '$' && i+1 < len(str) && isEnvNameChar(str[i+1]):
+			end := i + 1
+			for end < len(str) && isEnvNameChar(str[end]) {
+				end++
+			}
+			value, _ := lookup(str[i+1 : end])
+			sb.WriteString(value)
+			i = end - 1
+		default:
+			sb.WriteByte(str[i])
+		}
+	}
+	return sb.String()
+}
+
+func (c *Config) allDotEnvFiles() []string {
+	if c.parent == nil {
+		return c.dotenvFiles
+	}
+	return append(append([]string{}, c.parent.allDotEnvFiles()...), c.dotenvFiles...)
+}
@@ -0,0 +1,62 @@
+package conf
+
+import (
+	"net/http"
+
+	"github.com/enolgor/go-utils/server"
+)
+
+// Source tells where the resolved value of a setting comes from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+type Entry struct {
+	Env    string `json:"env,omitempty"`
+	Flag   string `json:"flag,omitempty"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+	Raw    string `json:"raw"`
+	// Profile is the profile whose default or file supplied the value, if any.
+	Profile string `json:"profile,omitempty"`
+}
+
+// Dump returns every registered setting with its resolved value, the source (and profile)
+// that supplied it and the raw string read from that source. Secret values are redacted.
+func (c *Config) Dump() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	settings := c.allSettings()
+	entries := make([]Entry, len(settings))
+	for i, s := range settings {
+		entries[i] = Entry{Env: s.envKey, Flag: s.flagKey, Type: s.typeName, Value: s.format(), Source: s.source, Raw: s.raw, Profile: s.profile}
+		if entries[i].Source == "" {
+			entries[i].Source, entries[i].Raw = SourceDefault, s.def
+		}
+		if s.secret {
+			entries[i].Value, entries[i].Raw = redacted, redacted
+		}
+	}
+	return entries
+}
+
+// DumpHandler serves the Dump as JSON, e.g. router.Get("/config", c.DumpHandler()).
+func (c *Config) DumpHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		server.Response(w).WithBody(c.Dump()).AsJson()
+	}
+}
+
+func Dump() []Entry {
+	return Default.Dump()
+}
+
+func DumpHandler() http.HandlerFunc {
+	return Default.DumpHandler()
+}
@@ -0,0 +1,87 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes an invalid value for a setting, either because it could not be
+// parsed or because it did not pass validation.
+type FieldError struct {
+	Env   string
+	Flag  string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf(`invalid value %s: %s`, e.describe(), e.Err.Error())
+}
+
+func (e *FieldError) describe() string {
+	return fmt.Sprintf(`"%s" for %s`, e.Value, describeKeys(e.Env, e.Flag))
+}
+
+func describeKeys(env, flag string) string {
+	keys := []string{}
+	if env != "" {
+		keys = append(keys, fmt.Sprintf(`env "%s"`, env))
+	}
+	if flag != "" {
+		keys = append(keys, fmt.Sprintf(`flag "%s"`, flag))
+	}
+	return strings.Join(keys, " or ")
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func (s *setting) fieldError(value string, err error) *FieldError {
+	if s == nil {
+		return &FieldError{Value: value, Err: err}
+	}
+	if s.secret {
+		value = redacted
+	}
+	return &FieldError{Env: s.envKey, Flag: s.flagKey, Value: value, Err: err}
+}
+
+// secretError replaces the parse errors of secrets, whose message may quote the value.
+type secretError struct {
+	err error
+}
+
+func (e *secretError) Error() string {
+	return "cannot parse secret value"
+}
+
+func (e *secretError) Unwrap() error {
+	return e.err
+}
+
+// parseError is the FieldError of a value that could not be parsed or decrypted.
+func (s *setting) parseError(value string, err error) *FieldError {
+	if s.secret {
+		err = &secretError{err}
+	}
+	return s.fieldError(value, err)
+}
+
+// RuleError describes a failed Rule, with a FieldError for every setting it depends on.
+type RuleError struct {
+	Fields []*FieldError
+	Err    error
+}
+
+func (e *RuleError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i := range e.Fields {
+		fields[i] = e.Fields[i].describe()
+	}
+	return fmt.Sprintf(`invalid combination of values %s: %s`, strings.Join(fields, ", "), e.Err.Error())
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,143 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/enolgor/go-utils/parse"
+	"gopkg.in/yaml.v3"
+)
+
+// FromFile adds a JSON, YAML or TOML file as a source, overridden by env and flags.
+// Nested keys are flattened to a dotted path matched against the env key (or flag key),
+// ignoring case and treating "_" and "-" as ".", so SERVER_PORT reads server.port.
+func (c *Config) FromFile(path string) {
+	c.files = append(c.files, path)
+}
+
+func FromFile(path string) {
+	Default.FromFile(path)
+}
+
+func normalizeKey(key string) string {
+	return strings.NewReplacer("_", ".", "-", ".").Replace(strings.ToLower(key))
+}
+
+func readFiles(paths []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, path := range paths {
+		data, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf(`cannot read config file "%s": %s`, path, err.Error())
+		}
+		flatten("", data, values)
+	}
+	return values, nil
+}
+
+func readFile(path string) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		err = dec.Decode(&data)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &data)
+	case ".toml":
+		err = toml.Unmarshal(content, &data)
+	default:
+		err = fmt.Errorf(`unsupported file extension "%s"`, ext)
+	}
+	return data, err
+}
+
+func flatten(prefix string, v any, values map[string]string) {
+	switch t := v.(type) {
+	case nil:
+	case map[string]any:
+		pairs := []string{}
+		for key, value := range t {
+			flatten(joinKey(prefix, key), value, values)
+			pairs = appendPair(pairs, key, value)
+		}
+		setPairs(prefix, pairs, len(t), values)
+	case map[any]any:
+		pairs := []string{}
+		for key, value := range t {
+			flatten(joinKey(prefix, fmt.Sprint(key)), value, values)
+			pairs = appendPair(pairs, fmt.Sprint(key), value)
+		}
+		setPairs(prefix, pairs, len(t), values)
+	case []any:
+		values[normalizeKey(prefix)] = list(t)
+	default:
+		values[normalizeKey(prefix)] = scalar(t)
+	}
+}
+
+// appendPair adds key=value to pairs when value is a scalar, so that a map of scalars
+// can also be read by a key-value setting.
+func appendPair(pairs []string, key string, value any) []string {
+	switch value.(type) {
+	case nil, map[string]any, map[any]any, []any:
+		return pairs
+	}
+	return append(pairs, escape(key)+"="+escape(scalar(value)))
+}
+
+func setPairs(prefix string, pairs []string, size int, values map[string]string) {
+	if prefix == "" || len(pairs) != size {
+		return
+	}
+	sort.Strings(pairs)
+	values[normalizeKey(prefix)] = strings.Join(pairs, ",")
+}
+
+// list formats a file array like parse.ArrayToString, nested arrays being separated by ";".
+func list(items []any) string {
+	parts := make([]string, len(items))
+	sep := ','
+	for i := range items {
+		if inner, ok := items[i].([]any); ok {
+			parts[i], sep = list(inner), ';'
+		} else {
+			parts[i] = scalar(items[i])
+		}
+	}
+	return parse.JoinList(parts, sep)
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func scalar(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32)
+	case time.Time:
+		return t.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -2,6 +2,14 @@ module github.com/enolgor/go-utils/conf
 
 go 1.21
 
-require github.com/enolgor/go-utils/parse v1.0.0
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/enolgor/go-utils/parse v1.3.0
+	github.com/enolgor/go-utils/sec v1.1.2
+	github.com/enolgor/go-utils/server v1.1.2
+	github.com/enolgor/go-utils/validators v1.3.0
+	golang.org/x/text v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
-require golang.org/x/text v0.12.0 // indirect
+require golang.org/x/crypto v0.12.0 // indirect
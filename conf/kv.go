@@ -0,0 +1,290 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/enolgor/go-utils/parse"
+)
+
+// KeyValue is a setting holding a key and a value of any of the types supported by
+// parse.Codec, the Set* functions panicking for unsupported ones as set does.
+type KeyValue[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// Key-value settings are written as comma separated key=value pairs ("a=1,b=2"). A literal
+// ",", "=" or "\" inside a key or value is escaped with a backslash ("a\,b=1"). The value
+// is everything after the first unescaped "=", so "k=YWJj==" has the value "YWJj==".
+// Repeated flags add pairs to those of the previous flags.
+
+func splitEscaped(str string, sep byte) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, str[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, str[start:])
+}
+
+func cutEscaped(str string, sep byte) (string, string, bool) {
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '\\':
+			i++
+		case sep:
+			return str[:i], str[i+1:], true
+		}
+	}
+	return str, "", false
+}
+
+func unescape(str string) string {
+	var sb strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) {
+			i++
+		}
+		sb.WriteByte(str[i])
+	}
+	return sb.String()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`)
+
+func escape(str string) string {
+	return escaper.Replace(str)
+}
+
+func parsePair[K, V any](str string) (KeyValue[K, V], error) {
+	var kv KeyValue[K, V]
+	key, value, ok := cutEscaped(strings.TrimSpace(str), '=')
+	if !ok {
+		return kv, fmt.Errorf(`"=" sign not found for key-value property "%s"`, str)
+	}
+	if err := parse.Parse(&kv.Key, unescape(key)); err != nil {
+		return kv, fmt.Errorf("invalid key, %s", err.Error())
+	}
+	if err := parse.Parse(&kv.Value, unescape(value)); err != nil {
+		return kv, fmt.Errorf("invalid value, %s", err.Error())
+	}
+	return kv, nil
+}
+
+func parseSinglePair[K, V any](str string) (KeyValue[K, V], error) {
+	if parts := splitEscaped(str, ','); len(parts) != 1 {
+		return KeyValue[K, V]{}, fmt.Errorf(`expected a single key-value property, got %d`, len(parts))
+	}
+	return parsePair[K, V](str)
+}
+
+func parseKVs[K, V any](str string) ([]KeyValue[K, V], error) {
+	kvs := []KeyValue[K, V]{}
+	for _, part := range splitEscaped(str, ',') {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		kv, err := parsePair[K, V](part)
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, nil
+}
+
+func parseMap[K comparable, V any](str string) (map[K]V, error) {
+	kvs, err := parseKVs[K, V](str)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[K]V, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m, nil
+}
+
+func formatPair[K, V any](kv KeyValue[K, V]) string {
+	return escape(parse.ToString(kv.Key)) + "=" + escape(parse.ToString(kv.Value))
+}
+
+func formatKVs[K, V any](kvs []KeyValue[K, V]) string {
+	parts := make([]string, len(kvs))
+	for i := range kvs {
+		parts[i] = formatPair(kvs[i])
+	}
+	return strings.Join(parts, ",")
+}
+
+// sortedPairs returns the map entries ordered by their formatted key.
+func sortedPairs[K comparable, V any](m map[K]V) []KeyValue[K, V] {
+	kvs := make([]KeyValue[K, V], 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, KeyValue[K, V]{k, v})
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return parse.ToString(kvs[i].Key) < parse.ToString(kvs[j].Key)
+	})
+	return kvs
+}
+
+func formatMap[K comparable, V any](m map[K]V) string {
+	return formatKVs(sortedPairs(m))
+}
+
+func pairTypeName[K, V any]() string {
+	var k K
+	var v V
+	return fmt.Sprintf("%T=%T", k, v)
+}
+
+func validatePair[K, V any](s *setting, kv *KeyValue[K, V], keyValidator func(*K) error, valueValidator func(*V) error, keyValueValidator func(*K, *V) error) error {
+	var err error
+	if keyValidator != nil {
+		if err = keyValidator(&kv.Key); err != nil {
+			err = fmt.Errorf("invalid key, %s", err.Error())
+		}
+	}
+	if err == nil && valueValidator != nil {
+		if err = valueValidator(&kv.Value); err != nil {
+			err = fmt.Errorf("invalid value, %s", err.Error())
+		}
+	}
+	if err == nil && keyValueValidator != nil {
+		if err = keyValueValidator(&kv.Key, &kv.Value); err != nil {
+			err = fmt.Errorf("invalid keyValue, %s", err.Error())
+		}
+	}
+	if err != nil {
+		return s.fieldError(formatPair(*kv), err)
+	}
+	return nil
+}
+
+func validatePairs[K, V any](s *setting, kvs []KeyValue[K, V], keyValidator func(*K) error, valueValidator func(*V) error) error {
+	errs := []error{}
+	for i := range kvs {
+		if err := validatePair(s, &kvs[i], keyValidator, valueValidator, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setCollection[T any](c *Config, take *T, envKey, flagKey string, def T, parser func(string) (T, error), formatter func(T) string, typeName string, validated bool, opts []Option) *setting {
+	*take = def
+	if envKey == "" && flagKey == "" {
+		return nil
+	}
+	s := newSetting(take, envKey, flagKey, def, parser, formatter)
+	s.typeName = typeName
+	s.validated = validated
+	return c.add(s, opts)
+}
+
+func SetPairValidateIn[K, V any](c *Config, take *KeyValue[K, V], envKey, flagKey string, def KeyValue[K, V], keyValidator func(*K) error, valueValidator func(*V) error, keyValueValidator func(*K, *V) error, opts ...Option) {
+	mustSupport[K]()
+	mustSupport[V]()
+	validated := keyValidator != nil || valueValidator != nil || keyValueValidator != nil
+	s := setCollection(c, take, envKey, flagKey, def, parseSinglePair[K, V], formatPair[K, V], pairTypeName[K, V](), validated, opts)
+	if s != nil && validated {
+		s.validate = func(v any) error {
+			kv := v.(KeyValue[K, V])
+			return validatePair(s, &kv, keyValidator, valueValidator, keyValueValidator)
+		}
+	}
+}
+
+func SetPairIn[K, V any](c *Config, take *KeyValue[K, V], envKey, flagKey string, def KeyValue[K, V], opts ...Option) {
+	SetPairValidateIn(c, take, envKey, flagKey, def, nil, nil, nil, opts...)
+}
+
+func SetEnvKVIn[K, V any](c *Config, take *KeyValue[K, V], envKey string, def KeyValue[K, V], opts ...Option) {
+	SetPairIn(c, take, envKey, "", def, opts...)
+}
+
+func SetFlagKVIn[K, V any](c *Config, take *KeyValue[K, V], flagKey string, def KeyValue[K, V], opts ...Option) {
+	SetPairIn(c, take, "", flagKey, def, opts...)
+}
+
+func SetKVsValidateIn[K, V any](c *Config, take *[]KeyValue[K, V], envKey, flagKey string, def []KeyValue[K, V], keyValidator func(*K) error, valueValidator func(*V) error, opts ...Option) {
+	mustSupport[K]()
+	mustSupport[V]()
+	validated := keyValidator != nil || valueValidator != nil
+	s := setCollection(c, take, envKey, flagKey, def, parseKVs[K, V], formatKVs[K, V], "[]"+pairTypeName[K, V](), validated, opts)
+	if s != nil {
+		s.repeatable = true
+	}
+	if s != nil && validated {
+		s.validate = func(v any) error {
+			return validatePairs(s, append([]KeyValue[K, V]{}, v.([]KeyValue[K, V])...), keyValidator, valueValidator)
+		}
+	}
+}
+
+func SetKVsIn[K, V any](c *Config, take *[]KeyValue[K, V], envKey, flagKey string, def []KeyValue[K, V], opts ...Option) {
+	SetKVsValidateIn(c, take, envKey, flagKey, def, nil, nil, opts...)
+}
+
+func SetMapValidateIn[K comparable, V any](c *Config, take *map[K]V, envKey, flagKey string, def map[K]V, keyValidator func(*K) error, valueValidator func(*V) error, opts ...Option) {
+	mustSupport[K]()
+	mustSupport[V]()
+	validated := keyValidator != nil || valueValidator != nil
+	s := setCollection(c, take, envKey, flagKey, maps.Clone(def), parseMap[K, V], formatMap[K, V], fmt.Sprintf("%T", def), validated, opts)
+	if s != nil {
+		s.repeatable = true
+		s.defValue = func() any { return maps.Clone(def) }
+	}
+	if s != nil && validated {
+		s.validate = func(v any) error {
+			return validatePairs(s, sortedPairs(v.(map[K]V)), keyValidator, valueValidator)
+		}
+	}
+}
+
+func SetMapIn[K comparable, V any](c *Config, take *map[K]V, envKey, flagKey string, def map[K]V, opts ...Option) {
+	SetMapValidateIn(c, take, envKey, flagKey, def, nil, nil, opts...)
+}
+
+func SetPairValidate[K, V any](take *KeyValue[K, V], envKey, flagKey string, def KeyValue[K, V], keyValidator func(*K) error, valueValidator func(*V) error, keyValueValidator func(*K, *V) error, opts ...Option) {
+	SetPairValidateIn(Default, take, envKey, flagKey, def, keyValidator, valueValidator, keyValueValidator, opts...)
+}
+
+func SetPair[K, V any](take *KeyValue[K, V], envKey, flagKey string, def KeyValue[K, V], opts ...Option) {
+	SetPairIn(Default, take, envKey, flagKey, def, opts...)
+}
+
+func SetEnvKV[K, V any](take *KeyValue[K, V], envKey string, def KeyValue[K, V], opts ...Option) {
+	SetEnvKVIn(Default, take, envKey, def, opts...)
+}
+
+func SetFlagKV[K, V any](take *KeyValue[K, V], flagKey string, def KeyValue[K, V], opts ...Option) {
+	SetFlagKVIn(Default, take, flagKey, def, opts...)
+}
+
+func SetKVsValidate[K, V any](take *[]KeyValue[K, V], envKey, flagKey string, def []KeyValue[K, V], keyValidator func(*K) error, valueValidator func(*V) error, opts ...Option) {
+	SetKVsValidateIn(Default, take, envKey, flagKey, def, keyValidator, valueValidator, opts...)
+}
+
+func SetKVs[K, V any](take *[]KeyValue[K, V], envKey, flagKey string, def []KeyValue[K, V], opts ...Option) {
+	SetKVsIn(Default, take, envKey, flagKey, def, opts...)
+}
+
+func SetMapValidate[K comparable, V any](take *map[K]V, envKey, flagKey string, def map[K]V, keyValidator func(*K) error, valueValidator func(*V) error, opts ...Option) {
+	SetMapValidateIn(Default, take, envKey, flagKey, def, keyValidator, valueValidator, opts...)
+}
+
+func SetMap[K comparable, V any](take *map[K]V, envKey, flagKey string, def map[K]V, opts ...Option) {
+	SetMapIn(Default, take, envKey, flagKey, def, opts...)
+}
@@ -0,0 +1,129 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SetPrefix sets the prefix of the env vars of the application, for example "MYAPP_".
+// Load then reports every env var (listed by the environ given to SetEnviron, or found in
+// dotenv files) with the prefix that is not the key of any setting, suggesting the closest
+// known key. It is written as a warning, or fails the load if SetPrefixStrict is set.
+func (c *Config) SetPrefix(prefix string) {
+	c.prefix = prefix
+}
+
+func SetPrefix(prefix string) {
+	Default.SetPrefix(prefix)
+}
+
+// SetPrefixStrict makes Load fail when an unknown prefixed env var is found instead of
+// writing a warning.
+func (c *Config) SetPrefixStrict(strict bool) {
+	c.prefixStrict = strict
+}
+
+func SetPrefixStrict(strict bool) {
+	Default.SetPrefixStrict(strict)
+}
+
+// SetEnviron sets the function listing the env vars as "key=value", like os.Environ, that
+// is scanned for unknown prefixed env vars. It defaults to os.Environ when New is given a
+// nil lookupEnv, and otherwise to none, as it must list the same env as lookupEnv.
+func (c *Config) SetEnviron(environ func() []string) {
+	c.environ = environ
+}
+
+func SetEnviron(environ func() []string) {
+	Default.SetEnviron(environ)
+}
+
+func (c *Config) unknownEnv() []error {
+	root := c.root()
+	if root.prefix == "" {
+		return nil
+	}
+	known := map[string]bool{}
+	root.knownEnv(known)
+	names := []string{}
+	if root.environ != nil {
+		for _, kv := range root.environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			names = append(names, name)
+		}
+	}
+	for name := range c.dotenv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := []error{}
+	for i, name := range names {
+		if !strings.HasPrefix(name, root.prefix) || known[name] || (i > 0 && names[i-1] == name) {
+			continue
+		}
+		msg := fmt.Sprintf(`unknown env "%s"`, name)
+		if suggestion := closest(root.prefix, name, known); suggestion != "" {
+			msg += fmt.Sprintf(`, did you mean "%s"?`, suggestion)
+		}
+		if root.prefixStrict {
+			errs = append(errs, errors.New(msg))
+		} else {
+			c.warn("%s", msg)
+		}
+	}
+	return errs
+}
+
+// knownEnv adds the env keys of the settings of c and its commands to known.
+func (c *Config) knownEnv(known map[string]bool) {
+	for _, s := range c.settings {
+		for _, key := range append([]string{s.envKey}, s.envAliases...) {
+			if key != "" {
+				known[key], known[key+"_FILE"] = true, true
+			}
+		}
+	}
+	for _, cmd := range c.commands {
+		cmd.config.knownEnv(known)
+	}
+}
+
+// closest returns the known key with the smallest edit distance to name after the
+// prefix, or "" if none is close enough to be a likely typo.
+func closest(prefix, name string, known map[string]bool) string {
+	name = strings.TrimPrefix(name, prefix)
+	best, bestDistance := "", len(name)/2+1
+	for key := range known {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		d := distance(name, strings.TrimPrefix(key, prefix))
+		if d < bestDistance || (d == bestDistance && key < best) {
+			best, bestDistance = key, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,72 @@
+package conf
+
+import "fmt"
+
+// Profiles registers the setting that selects the active profile, for example
+// Profiles("APP_PROFILE", "profile"). It is resolved before every other setting so that
+// the defaults given with ProfileDefault and the files added with FromProfileFile for
+// that profile are layered over the base ones.
+func (c *Config) Profiles(envKey, flagKey string) {
+	c.profile = set(c, &c.profileName, envKey, flagKey, "", Description("configuration profile"))
+}
+
+func Profiles(envKey, flagKey string) {
+	Default.Profiles(envKey, flagKey)
+}
+
+// Profile returns the active profile after Load, or "" if there is none.
+func (c *Config) Profile() string {
+	if owner := c.profileOwner(); owner != nil {
+		return owner.profileName
+	}
+	return ""
+}
+
+func Profile() string {
+	return Default.Profile()
+}
+
+// FromProfileFile is like FromFile but the file is only read when profile is active,
+// overriding the values of the base files.
+func (c *Config) FromProfileFile(profile, path string) {
+	c.profileFiles[profile] = append(c.profileFiles[profile], path)
+}
+
+func FromProfileFile(profile, path string) {
+	Default.FromProfileFile(profile, path)
+}
+
+// ProfileDefault sets the default value of the setting when profile is active. It panics
+// if def is not of the setting type.
+func ProfileDefault[T any](profile string, def T) Option {
+	return func(s *setting) {
+		if _, ok := s.get().(T); !ok {
+			panic(fmt.Sprintf("conf: profile %s default of type %T for a setting of type %s", profile, def, s.typeName))
+		}
+		if s.profileDefaults == nil {
+			s.profileDefaults = map[string]any{}
+		}
+		s.profileDefaults[profile] = def
+	}
+}
+
+func (c *Config) profileOwner() *Config {
+	if c.profile == nil && c.parent != nil {
+		return c.parent.profileOwner()
+	}
+	if c.profile == nil {
+		return nil
+	}
+	return c
+}
+
+func (c *Config) allProfileFiles(profile string) []string {
+	files := []string{}
+	if c.parent != nil {
+		files = c.parent.allProfileFiles(profile)
+	}
+	if profile == "" {
+		return files
+	}
+	return append(files, c.profileFiles[profile]...)
+}
@@ -0,0 +1,130 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Reload resolves every setting again from files, env and the flags given to the last
+// Load and runs the validators on the new values before writing any of them, so if
+// anything fails all the settings keep their previous values and the errors are
+// returned. Rules run once the new values are written, which are reverted if a rule
+// fails. OnChange subscribers are then notified for every setting whose value changed.
+func (c *Config) Reload() error {
+	c.mu.Lock()
+	errs := append(c.resolve(), c.validate()...)
+	if len(errs) > 0 {
+		c.mu.Unlock()
+		return errors.Join(errs...)
+	}
+	old, errs := c.commit()
+	if len(errs) > 0 {
+		c.mu.Unlock()
+		return errors.Join(errs...)
+	}
+	c.publish()
+	notify := []func(){}
+	for i, s := range c.allSettings() {
+		if s.format() == s.formatValue(old[i]) {
+			continue
+		}
+		for _, fn := range s.onChange {
+			fn, old, new := fn, old[i], s.get()
+			notify = append(notify, func() { fn(old, new) })
+		}
+	}
+	c.mu.Unlock()
+	for _, fn := range notify {
+		fn()
+	}
+	return nil
+}
+
+// Watch blocks until ctx is done, calling Reload when a config file modification time
+// changes (checked every interval) or when the process receives SIGHUP. Reload errors
+// are passed to onError, which may be nil.
+func (c *Config) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	modTimes := c.modTimes()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			current := c.modTimes()
+			if equalTimes(current, modTimes) {
+				continue
+			}
+			modTimes = current
+		}
+		if err := c.Reload(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+func (c *Config) modTimes() []time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files := append(append(append([]string{}, c.allFiles()...), c.allDotEnvFiles()...), c.allProfileFiles(c.Profile())...)
+	times := make([]time.Time, len(files))
+	for i, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+	return times
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Config) findSetting(ptr any) *setting {
+	for _, s := range c.allSettings() {
+		if s.ptr == ptr {
+			return s
+		}
+	}
+	panic(fmt.Sprintf("conf: %T is not a registered setting", ptr))
+}
+
+// OnChangeIn subscribes fn to the changes of the setting bound to take after a Reload.
+func OnChangeIn[T any](c *Config, take *T, fn func(old, new T)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.findSetting(take)
+	s.onChange = append(s.onChange, func(old, new any) {
+		fn(old.(T), new.(T))
+	})
+}
+
+func OnChange[T any](take *T, fn func(old, new T)) {
+	OnChangeIn(Default, take, fn)
+}
+
+func Reload() error {
+	return Default.Reload()
+}
+
+func Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	Default.Watch(ctx, interval, onError)
+}
@@ -0,0 +1,30 @@
+package conf
+
+// Rule adds a validation that involves several settings, such as "TLS_CERT is required
+// when TLS is true" or "MIN < MAX". It runs after every setting is resolved, along with
+// the other validators, and its error is reported as a *RuleError naming the env and flag
+// of every dep. Each dep must be the pointer of a registered setting, otherwise it panics.
+func (c *Config) Rule(rule func() error, deps ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	settings := make([]*setting, len(deps))
+	for i, dep := range deps {
+		settings[i] = c.findSetting(dep)
+		settings[i].validated = true
+	}
+	c.rules = append(c.rules, func() error {
+		err := rule()
+		if err == nil {
+			return nil
+		}
+		fields := make([]*FieldError, len(settings))
+		for i, s := range settings {
+			fields[i] = s.fieldError(s.format(), err)
+		}
+		return &RuleError{Fields: fields, Err: err}
+	})
+}
+
+func Rule(rule func() error, deps ...any) {
+	Default.Rule(rule, deps...)
+}
@@ -0,0 +1,148 @@
+package conf
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/enolgor/go-utils/parse"
+	"github.com/enolgor/go-utils/parse/types"
+)
+
+var typesPkgPath = reflect.TypeOf(types.HexByte(0)).PkgPath()
+
+// Schema writes a JSON Schema of the registered settings, with a property named after the
+// env key (or the flag key) of each setting. The property has the JSON type of the setting,
+// its default, its description and, for parse.Enum types and settings with the Enum option
+// or a oneof Bind rule, an enum with the accepted values. A setting is required when
+// its default does not pass its validator. Secrets are marked writeOnly and their default
+// is omitted.
+func (c *Config) Schema(w io.Writer) error {
+	c.mu.Lock()
+	settings := c.allSettings()
+	c.mu.Unlock()
+	properties := map[string]any{}
+	required := []string{}
+	for _, s := range settings {
+		name := s.envKey
+		if name == "" {
+			name = s.flagKey
+		}
+		property := typeSchema(reflect.TypeOf(s.ptr).Elem())
+		if s.description != "" {
+			property["description"] = s.description
+		}
+		if s.secret {
+			property["writeOnly"] = true
+		} else if def, ok := jsonValue(property, s.def); ok {
+			property["default"] = def
+		}
+		if s.validate != nil && s.validate(s.defValue()) != nil {
+			required = append(required, name)
+		}
+		if s.enum != nil {
+			property["enum"] = s.enum
+		}
+		properties[name] = property
+	}
+	schema := map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+func Schema(w io.Writer) error {
+	return Default.Schema(w)
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	if names := parse.EnumNames(t); names != nil {
+		return map[string]any{"type": "string", "enum": names}
+	}
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)) || t == reflect.TypeOf(os.FileMode(0)) || t.PkgPath() == typesPkgPath:
+		return map[string]any{"type": "string"}
+	case t == reflect.TypeOf(&url.URL{}):
+		return map[string]any{"type": "string", "format": "uri"}
+	case t == reflect.TypeOf(&regexp.Regexp{}):
+		return map[string]any{"type": "string", "format": "regex"}
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// jsonValue converts the formatted value str to the JSON type of the property.
+func jsonValue(property map[string]any, str string) (any, bool) {
+	switch property["type"] {
+	case "integer":
+		i, err := strconv.ParseInt(str, 10, 64)
+		return i, err == nil
+	case "number":
+		f, err := strconv.ParseFloat(str, 64)
+		return f, err == nil
+	case "boolean":
+		b, err := strconv.ParseBool(str)
+		return b, err == nil
+	case "array":
+		itemProperty := property["items"].(map[string]any)
+		sep := ','
+		if itemProperty["type"] == "array" {
+			sep = ';'
+		}
+		parts, err := parse.SplitList(str, sep)
+		if err != nil {
+			return nil, false
+		}
+		items := []any{}
+		for _, part := range parts {
+			item, ok := jsonValue(itemProperty, part)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, item)
+		}
+		return items, true
+	case "object":
+		object := map[string]any{}
+		for _, part := range splitEscaped(str, ',') {
+			if part == "" {
+				continue
+			}
+			key, value, _ := cutEscaped(part, '=')
+			item, ok := jsonValue(property["additionalProperties"].(map[string]any), unescape(value))
+			if !ok {
+				return nil, false
+			}
+			object[unescape(key)] = item
+		}
+		return object, true
+	}
+	return str, true
+}
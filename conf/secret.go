@@ -0,0 +1,59 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/enolgor/go-utils/sec"
+)
+
+const redacted = "[REDACTED]"
+
+const encryptedPrefix = "enc:"
+
+// Secret marks a setting as sensitive: its value is redacted from usage and error output,
+// and values carrying the "enc:" prefix are decrypted with the crypto given to SetCrypto.
+var Secret Option = func(s *setting) {
+	s.secret = true
+}
+
+// SetCrypto sets the crypto used to decrypt secret values with the "enc:" prefix.
+func (c *Config) SetCrypto(crypto sec.Crypto) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.crypto = crypto
+}
+
+func SetCrypto(crypto sec.Crypto) {
+	Default.SetCrypto(crypto)
+}
+
+// lookupEnvOrFile returns the value of the env var key or, following the Docker and
+// Kubernetes secrets convention, the content of the file named by key_FILE.
+func (c *Config) lookupEnvOrFile(key string) (string, bool, error) {
+	if str, ok := c.getEnv(key); ok && str != "" {
+		return str, true, nil
+	}
+	path, ok := c.getEnv(key + "_FILE")
+	if !ok || path == "" {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+func (c *Config) reveal(s *setting, str string) (string, error) {
+	if !s.secret || !strings.HasPrefix(str, encryptedPrefix) {
+		return str, nil
+	}
+	crypto := c.cryptoOrInherited()
+	if crypto == nil {
+		return "", errors.New("encrypted value but no crypto set")
+	}
+	plain, err := crypto.Decrypt(strings.TrimPrefix(str, encryptedPrefix))
+	return string(plain), err
+}
@@ -0,0 +1,84 @@
+package conf
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/enolgor/go-utils/parse"
+)
+
+type usageRow struct {
+	flag, env, typeName, def, validated, description string
+}
+
+func (c *Config) usageRows() []usageRow {
+	rows := []usageRow{}
+	for _, s := range c.allSettings() {
+		row := usageRow{typeName: s.typeName, def: s.def, validated: "no", description: s.description}
+		if names := parse.EnumNames(reflect.TypeOf(s.ptr).Elem()); names != nil {
+			row.typeName += " (" + strings.Join(names, "|") + ")"
+		}
+		if s.secret && s.def != "" {
+			row.def = redacted
+		}
+		if s.flagKey != "" {
+			row.flag = "-" + s.flagKey
+		}
+		row.env = s.envKey
+		if s.validated {
+			row.validated = "yes"
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Usage writes a table with the flag, env var, type (with the members of parse.Enum types),
+// default value, whether validation applies and the description of every registered
+// setting. It is also used as the flag set usage function, so it is printed for -h, except
+// for flag.CommandLine when the program sets its own flag.Usage.
+func (c *Config) Usage(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  FLAG\tENV\tTYPE\tDEFAULT\tVALIDATED\tDESCRIPTION")
+	for _, row := range c.usageRows() {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", row.flag, row.env, row.typeName, row.def, row.validated, row.description)
+	}
+	if len(c.commands) > 0 {
+		fmt.Fprintln(tw, "\n  COMMAND\tDESCRIPTION")
+		for _, cmd := range c.commands {
+			fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.description)
+		}
+	}
+	tw.Flush()
+}
+
+// Markdown writes the same table as Usage formatted as Markdown.
+func (c *Config) Markdown(w io.Writer) {
+	fmt.Fprintln(w, "| Flag | Env | Type | Default | Validated | Description |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- | --- | --- |")
+	for _, row := range c.usageRows() {
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s |\n", code(row.flag), code(row.env), code(row.typeName), code(row.def), row.validated, escapeMarkdown(row.description))
+	}
+}
+
+func code(str string) string {
+	if str == "" {
+		return ""
+	}
+	return "`" + escapeMarkdown(str) + "`"
+}
+
+func escapeMarkdown(str string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(str)
+}
+
+func Usage(w io.Writer) {
+	Default.Usage(w)
+}
+
+func Markdown(w io.Writer) {
+	Default.Markdown(w)
+}
@@ -0,0 +1,55 @@
+package conf
+
+import "sync/atomic"
+
+// Value holds a setting that is safe to read while the configuration is reloaded. The
+// resolved value is published atomically once Load or a successful Reload finishes, so
+// Get never observes a partially applied or invalid configuration.
+type Value[T any] struct {
+	c       *Config
+	s       *setting
+	current atomic.Pointer[T]
+	staged  T
+}
+
+// NewValueIn registers a setting like SetValidateIn (validator may be nil) and returns
+// its Value.
+func NewValueIn[T any](c *Config, envKey, flagKey string, def T, validator func(*T) error, opts ...Option) *Value[T] {
+	v := &Value[T]{c: c}
+	v.current.Store(&def)
+	s := set(c, &v.staged, envKey, flagKey, def, opts...)
+	v.s = s
+	if s != nil {
+		s.publish = func() {
+			value := v.staged
+			v.current.Store(&value)
+		}
+	}
+	wrap(s, validator)
+	return v
+}
+
+func NewValue[T any](envKey, flagKey string, def T, validator func(*T) error, opts ...Option) *Value[T] {
+	return NewValueIn(Default, envKey, flagKey, def, validator, opts...)
+}
+
+func (v *Value[T]) Get() T {
+	return *v.current.Load()
+}
+
+// Watch subscribes fn to the changes of the value after a Reload. It is called once the
+// new value is returned by Get.
+func (v *Value[T]) Watch(fn func(old, new T)) {
+	if v.s == nil {
+		return
+	}
+	OnChangeIn(v.c, &v.staged, fn)
+}
+
+func (c *Config) publish() {
+	for _, s := range c.allSettings() {
+		if s.publish != nil {
+			s.publish()
+		}
+	}
+}
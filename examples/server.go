@@ -63,28 +63,25 @@ func loginHandler(signer *jwtauth.Signer) func(w http.ResponseWriter, req *http.
 			server.Response(w).Status(http.StatusBadRequest).WithBody(err).AsTextPlain()
 			return
 		}
-		var errs error
-		if !req.Form.Has("user") {
-			errs = errors.Join(errs, errors.New("user missing in form"))
+		var login struct {
+			User string `form:"user,required"`
+			Pass string `form:"pass,required"`
 		}
-		if !req.Form.Has("pass") {
-			errs = errors.Join(errs, errors.New("pass missing in form"))
-		}
-		if errs != nil {
-			server.Response(w).Status(http.StatusBadRequest).WithBody(errs).AsTextPlain()
+		if err := parse.Decode(&login, req.Form); err != nil {
+			server.Response(w).Status(http.StatusBadRequest).WithBody(err).AsTextPlain()
 			return
 		}
 		var hash string
 		var ok bool
-		if hash, ok = hashedPasswords[req.Form.Get("user")]; !ok {
+		if hash, ok = hashedPasswords[login.User]; !ok {
 			server.Response(w).Status(http.StatusBadRequest).WithBody(errors.New("user not found")).AsTextPlain()
 			return
 		}
-		if sec.ComparePassword(hash, req.Form.Get("pass")) != nil {
+		if sec.ComparePassword(hash, login.Pass) != nil {
 			server.Response(w).Status(http.StatusBadRequest).WithBody(errors.New("wrong password")).AsTextPlain()
 			return
 		}
-		token, exp, err := signer.ForgeToken(req.Form.Get("user"))
+		token, exp, err := signer.ForgeToken(login.User)
 		if err != nil {
 			server.Response(w).Status(http.StatusInternalServerError).WithBody(err).AsTextPlain()
 			return
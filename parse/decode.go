@@ -0,0 +1,173 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// ErrMissing is the error of the FieldError of a required key that is not present.
+var ErrMissing = errors.New("missing value")
+
+// FieldError describes a value that Decode could not set into a field.
+type FieldError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if errors.Is(e.Err, ErrMissing) {
+		return fmt.Sprintf(`missing value for "%s"`, e.Key)
+	}
+	return fmt.Sprintf(`invalid value "%s" for "%s": %s`, e.Value, e.Key, e.Err.Error())
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Decode sets the exported fields of the struct pointed by dst from values, such as the
+// url.Values of a parsed form or query, using the parser of each field type (see Codec):
+//
+//	var login struct {
+//		User     string   `form:"user,required"`
+//		Remember bool     `form:"remember" default:"false"`
+//		Scopes   []string `form:"scope"`
+//	}
+//	err := parse.Decode(&login, req.Form)
+//
+// The key of a field is its form tag, or its name if it has none, and a "-" tag skips it.
+// Fields of embedded structs, exported or not, are decoded as fields of dst, embedded
+// pointers being allocated when any of their fields is set. Slices (other than named ones
+// like types.HexBytes) take an element from every value of a repeated key, the other
+// fields the first value. Absent keys take the default tag value if any, which is a list
+// for slices (default:"a,b"), are reported if required and are otherwise left unchanged.
+// Instead of stopping at the first problem all of them are returned joined, each one as
+// a *FieldError.
+func Decode(dst any, values url.Values) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("parse: Decode expects a pointer to a struct, got %T", dst)
+	}
+	_, errs := decodeStruct(v.Elem(), values)
+	return errors.Join(errs...)
+}
+
+// DecodeMap is like Decode with a single value for every key.
+func DecodeMap(dst any, values map[string]string) error {
+	multi := make(url.Values, len(values))
+	for key, value := range values {
+		multi[key] = []string{value}
+	}
+	return Decode(dst, multi)
+}
+
+// decodeStruct returns whether any field of v was set and the errors of the fields.
+func decodeStruct(v reflect.Value, values url.Values) (bool, []error) {
+	errs := []error{}
+	set := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, hasTag := field.Tag.Lookup("form")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && !hasTag {
+			embedded, fieldSet, fieldErrs := decodeEmbedded(v.Field(i), values)
+			if embedded {
+				set = set || fieldSet
+				errs = append(errs, fieldErrs...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		key, opts, _ := strings.Cut(tag, ",")
+		if key == "" {
+			key = field.Name
+		}
+		var err error
+		if raws, ok := values[key]; ok && len(raws) > 0 {
+			err = decodeField(v.Field(i), key, raws)
+		} else if def, ok := field.Tag.Lookup("default"); ok {
+			err = decodeValue(v.Field(i), key, def)
+		} else {
+			if opts == "required" {
+				errs = append(errs, &FieldError{Key: key, Err: ErrMissing})
+			}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			set = true
+		}
+	}
+	return set, errs
+}
+
+// decodeEmbedded decodes the fields of an embedded struct, exported or not, into v,
+// allocating it if it is a nil pointer and any of its fields is set. It reports false if
+// v is not a struct or a pointer to one.
+func decodeEmbedded(v reflect.Value, values url.Values) (bool, bool, []error) {
+	switch {
+	case v.Kind() == reflect.Struct:
+		set, errs := decodeStruct(v, values)
+		return true, set, errs
+	case v.Kind() != reflect.Pointer || v.Type().Elem().Kind() != reflect.Struct:
+		return false, false, nil
+	case !v.IsNil():
+		set, errs := decodeStruct(v.Elem(), values)
+		return true, set, errs
+	}
+	elem := reflect.New(v.Type().Elem())
+	set, errs := decodeStruct(elem.Elem(), values)
+	if set {
+		if !v.CanSet() {
+			err := fmt.Errorf("cannot allocate the embedded pointer to the unexported struct %s", v.Type().Elem())
+			return true, false, append(errs, &FieldError{Key: v.Type().Elem().Name(), Err: err})
+		}
+		v.Set(elem)
+	}
+	return true, set, errs
+}
+
+// decodeField sets v from the values of a key, taking an element from every value for
+// slices (other than named ones) and the first value for other types.
+func decodeField(v reflect.Value, key string, raws []string) error {
+	t := v.Type()
+	if t.Kind() == reflect.Slice && t.Name() == "" {
+		if parser, _, ok := Codec(t.Elem()); ok {
+			slice := reflect.MakeSlice(t, 0, len(raws))
+			for _, raw := range raws {
+				elem, err := parser(raw)
+				if err != nil {
+					return &FieldError{Key: key, Value: raw, Err: err}
+				}
+				slice = reflect.Append(slice, reflect.ValueOf(elem))
+			}
+			v.Set(slice)
+			return nil
+		}
+	}
+	return decodeValue(v, key, raws[0])
+}
+
+// decodeValue sets v from a single value, such as a default read as a list for slices.
+func decodeValue(v reflect.Value, key, raw string) error {
+	parser, _, ok := Codec(v.Type())
+	if !ok {
+		return &FieldError{Key: key, Value: raw, Err: fmt.Errorf("unsupported type %s", v.Type())}
+	}
+	parsed, err := parser(raw)
+	if err != nil {
+		return &FieldError{Key: key, Value: raw, Err: err}
+	}
+	v.Set(reflect.ValueOf(parsed))
+	return nil
+}
@@ -0,0 +1,72 @@
+package parse
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	type Paging struct {
+		Page  int `form:"page" default:"1"`
+		Limit int `form:"limit" default:"20"`
+	}
+	type search struct {
+		Paging
+		Query  string        `form:"q,required"`
+		Tags   []string      `form:"tag"`
+		Within time.Duration `form:"within"`
+		Debug  bool          `form:"-"`
+		Name   string
+		hidden string
+	}
+	var dst search
+	values := url.Values{"q": {"go"}, "tag": {"a,b", "c"}, "limit": {"50"}, "within": {"1h"}, "Name": {"x"}, "Debug": {"true"}}
+	if err := Decode(&dst, values); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Query != "go" || len(dst.Tags) != 2 || dst.Tags[0] != "a,b" || dst.Page != 1 || dst.Limit != 50 || dst.Within != time.Hour || dst.Debug || dst.Name != "x" {
+		t.Errorf("got %+v", dst)
+	}
+	err := Decode(&dst, url.Values{"limit": {"many"}, "within": {"1h"}})
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || !errors.Is(err, ErrMissing) {
+		t.Fatalf("got %v", err)
+	}
+	expected := "invalid value \"many\" for \"limit\": strconv.Atoi: parsing \"many\": invalid syntax\nmissing value for \"q\""
+	if err.Error() != expected {
+		t.Errorf("got %q", err.Error())
+	}
+	var m struct {
+		Port uint16 `form:"port"`
+	}
+	if err := DecodeMap(&m, map[string]string{"port": "8080"}); err != nil || m.Port != 8080 {
+		t.Errorf("got %d %v", m.Port, err)
+	}
+	type base struct {
+		ID string `form:"id"`
+	}
+	type Extra struct {
+		Note string `form:"note"`
+	}
+	type Other struct {
+		Skipped string `form:"skipped"`
+	}
+	var item struct {
+		base
+		*Extra
+		*Other
+		Codes []int    `form:"code" default:"1,2"`
+		Names []string `form:"name" default:"a,b"`
+	}
+	if err := Decode(&item, url.Values{"id": {"7"}, "note": {"n"}}); err != nil {
+		t.Fatal(err)
+	}
+	if item.ID != "7" || item.Extra == nil || item.Note != "n" || item.Other != nil || len(item.Codes) != 2 || item.Codes[1] != 2 || len(item.Names) != 2 || item.Names[1] != "b" {
+		t.Errorf("got %+v", item)
+	}
+	if err := Decode(m, nil); err == nil {
+		t.Error("expected error for a non pointer destination")
+	}
+}
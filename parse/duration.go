@@ -0,0 +1,236 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+var errOverflow = errors.New("out of range")
+
+// DurationParser parses durations as time.ParseDuration does and, if enabled, with the
+// units d (24h) and w (7d) such as 7d or 1w2d12h, and in ISO-8601 such as P1DT2H or
+// PT1.5S. ISO-8601 years and months are rejected, they have no fixed duration.
+type DurationParser struct {
+	Days    bool
+	ISO8601 bool
+}
+
+// DefaultDurationParser is used by Duration and DurationToString and so by GetParser, Parse
+// and ToString for time.Duration values. Its options are disabled, so its values can be read
+// by time.ParseDuration, and can be enabled before parsing any value.
+var DefaultDurationParser = &DurationParser{}
+
+func (p *DurationParser) Parse(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	d, err := time.ParseDuration(str)
+	if err == nil || !p.Days && !p.ISO8601 {
+		return d, err
+	}
+	body, neg := str, false
+	if body != "" && (body[0] == '-' || body[0] == '+') {
+		body, neg = body[1:], body[0] == '-'
+	}
+	switch {
+	case p.ISO8601 && strings.HasPrefix(body, "P"):
+		d, err = parseISO8601(body[1:])
+	case p.Days:
+		d, err = parseDays(body)
+	default:
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf(`invalid duration "%s": %s`, str, err.Error())
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// Format returns the shortest form of d that Parse accepts, like 1h30m instead of
+// 1h30m0s, and with days and weeks such as 1w2d if Days is enabled.
+func (p *DurationParser) Format(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	var b strings.Builder
+	u := uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+	if u < uint64(time.Second) {
+		return b.String() + time.Duration(u).String()
+	}
+	units := []struct {
+		name string
+		size time.Duration
+	}{{"w", week}, {"d", day}, {"h", time.Hour}, {"m", time.Minute}}
+	if !p.Days {
+		units = units[2:]
+	}
+	for _, unit := range units {
+		if n := u / uint64(unit.size); n > 0 {
+			b.WriteString(strconv.FormatUint(n, 10) + unit.name)
+			u %= uint64(unit.size)
+		}
+	}
+	if u > 0 {
+		sec, frac := u/uint64(time.Second), u%uint64(time.Second)
+		b.WriteString(strconv.FormatUint(sec, 10))
+		if frac > 0 {
+			b.WriteString(strings.TrimRight(fmt.Sprintf(".%09d", frac), "0"))
+		}
+		b.WriteByte('s')
+	}
+	return b.String()
+}
+
+var goUnits = map[string]bool{"ns": true, "us": true, "µs": true, "μs": true, "ms": true, "s": true, "m": true, "h": true}
+
+// parseDays parses the d and w units of str and the other ones with time.ParseDuration.
+func parseDays(str string) (time.Duration, error) {
+	if str == "" {
+		return 0, errors.New("empty duration")
+	}
+	var total time.Duration
+	var rest strings.Builder
+	for str != "" {
+		i := numberLen(str)
+		if i == 0 {
+			return 0, fmt.Errorf(`expected a number at "%s"`, str)
+		}
+		j := i
+		for j < len(str) && str[j] != '.' && (str[j] < '0' || str[j] > '9') {
+			j++
+		}
+		number, unit := str[:i], str[i:j]
+		switch {
+		case unit == "d" || unit == "w":
+			size := day
+			if unit == "w" {
+				size = week
+			}
+			v, err := scaled(number, size)
+			if err != nil {
+				return 0, err
+			}
+			if total, err = add(total, v); err != nil {
+				return 0, err
+			}
+		case unit == "":
+			return 0, fmt.Errorf(`missing unit after "%s"`, number)
+		case goUnits[unit]:
+			rest.WriteString(str[:j])
+		default:
+			return 0, fmt.Errorf(`unknown unit "%s"`, unit)
+		}
+		str = str[j:]
+	}
+	if rest.Len() > 0 {
+		v, err := time.ParseDuration(rest.String())
+		if err != nil {
+			return 0, err
+		}
+		return add(total, v)
+	}
+	return total, nil
+}
+
+// parseISO8601 parses the part of an ISO-8601 duration after the P designator.
+func parseISO8601(str string) (time.Duration, error) {
+	if str == "" || str == "T" || strings.HasSuffix(str, "T") {
+		return 0, errors.New("missing components")
+	}
+	date, clock, _ := strings.Cut(str, "T")
+	dateUnits := map[byte]time.Duration{'W': week, 'D': day}
+	clockUnits := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var total time.Duration
+	for _, part := range []struct {
+		str   string
+		order string
+		units map[byte]time.Duration
+	}{{date, "YMWD", dateUnits}, {clock, "HMS", clockUnits}} {
+		last := -1
+		for s := part.str; s != ""; {
+			i := numberLen(s)
+			if i == 0 || i == len(s) {
+				return 0, fmt.Errorf(`expected a number and a designator at "%s"`, s)
+			}
+			number, designator := strings.ReplaceAll(s[:i], ",", "."), s[i]
+			pos := strings.IndexByte(part.order, designator)
+			if pos <= last {
+				return 0, fmt.Errorf(`unexpected designator "%c"`, designator)
+			}
+			last = pos
+			size, ok := part.units[designator]
+			if !ok {
+				return 0, errors.New("years and months have no fixed duration")
+			}
+			v, err := scaled(number, size)
+			if err != nil {
+				return 0, err
+			}
+			if total, err = add(total, v); err != nil {
+				return 0, err
+			}
+			s = s[i+1:]
+		}
+	}
+	return total, nil
+}
+
+func numberLen(str string) int {
+	i := 0
+	for i < len(str) && (str[i] >= '0' && str[i] <= '9' || str[i] == '.' || str[i] == ',') {
+		i++
+	}
+	return i
+}
+
+// scaled returns the decimal number times unit.
+func scaled(number string, unit time.Duration) (time.Duration, error) {
+	whole, frac, _ := strings.Cut(number, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf(`invalid number "%s"`, number)
+	}
+	var d time.Duration
+	if whole != "" {
+		n, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf(`invalid number "%s"`, number)
+		}
+		if n > math.MaxInt64/int64(unit) {
+			return 0, errOverflow
+		}
+		d = time.Duration(n) * unit
+	}
+	scale := unit
+	for _, digit := range frac {
+		if digit < '0' || digit > '9' {
+			return 0, fmt.Errorf(`invalid number "%s"`, number)
+		}
+		scale /= 10
+		d += time.Duration(digit-'0') * scale
+	}
+	if d < 0 {
+		return 0, errOverflow
+	}
+	return d, nil
+}
+
+func add(a, b time.Duration) (time.Duration, error) {
+	if a > math.MaxInt64-b {
+		return 0, errOverflow
+	}
+	return a + b, nil
+}
@@ -0,0 +1,77 @@
+package parse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationParser(t *testing.T) {
+	p := &DurationParser{Days: true, ISO8601: true}
+	cases := map[string]time.Duration{
+		"1h30m":       90 * time.Minute,
+		"7d":          7 * 24 * time.Hour,
+		"1w2d12h":     9*24*time.Hour + 12*time.Hour,
+		"1.5d":        36 * time.Hour,
+		"-2d":         -48 * time.Hour,
+		"P1DT2H":      26 * time.Hour,
+		"P2W":         14 * 24 * time.Hour,
+		"PT1,5S":      1500 * time.Millisecond,
+		"PT0.25M":     15 * time.Second,
+		"-P1D":        -24 * time.Hour,
+		"250ms":       250 * time.Millisecond,
+		"1d0.000001s": 24*time.Hour + time.Microsecond,
+	}
+	for str, expected := range cases {
+		if d, err := p.Parse(str); err != nil || d != expected {
+			t.Errorf("%s: got %v %v", str, d, err)
+		}
+	}
+	for _, str := range []string{"P1Y", "P1M", "PT", "P1H", "PT1D", "7x", "d", "P1DT2H3H", "99999999w"} {
+		if _, err := p.Parse(str); err == nil {
+			t.Errorf("%s: expected error", str)
+		}
+	}
+	if _, err := p.Parse("1d1,5h"); err == nil || err.Error() != `invalid duration "1d1,5h": time: unknown unit "," in duration "1,5h"` {
+		t.Errorf("got %v", err)
+	}
+	iso := &DurationParser{ISO8601: true}
+	if _, err := iso.Parse("bogus"); err == nil || err.Error() != `time: invalid duration "bogus"` {
+		t.Errorf("got %v", err)
+	}
+	if d, err := iso.Parse("PT1M"); err != nil || d != time.Minute {
+		t.Errorf("got %v %v", d, err)
+	}
+	formats := map[time.Duration]string{
+		0:                                 "0s",
+		90 * time.Minute:                  "1h30m",
+		24 * time.Hour:                    "1d",
+		9*24*time.Hour + 12*time.Hour:     "1w2d12h",
+		-36 * time.Hour:                   "-1d12h",
+		time.Hour + 1500*time.Millisecond: "1h1.5s",
+		150 * time.Millisecond:            "150ms",
+	}
+	for d, expected := range formats {
+		if str := p.Format(d); str != expected {
+			t.Errorf("%v: got %s", d, str)
+		} else if again, err := p.Parse(str); err != nil || again != d {
+			t.Errorf("%s does not round-trip: %v %v", str, again, err)
+		}
+	}
+}
+
+func TestDefaultDuration(t *testing.T) {
+	for _, str := range []string{"7d", "P1D"} {
+		if _, err := Duration(str); err == nil {
+			t.Errorf("%s: expected the extensions to be disabled", str)
+		}
+	}
+	for _, d := range []time.Duration{48 * time.Hour, 90 * time.Minute, time.Hour + 1500*time.Millisecond, -time.Millisecond} {
+		str := DurationToString(d)
+		if again, err := time.ParseDuration(str); err != nil || again != d {
+			t.Errorf("%s is not read back by time.ParseDuration: %v %v", str, again, err)
+		}
+	}
+	if str := DurationToString(48 * time.Hour); str != "48h" {
+		t.Errorf("got %s", str)
+	}
+}
@@ -0,0 +1,79 @@
+package parse
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Enum restricts the values of T to the ones of a list of names, matched ignoring case.
+type Enum[T comparable] struct {
+	names     []string
+	values    map[string]T
+	canonical map[T]string
+}
+
+// EnumMember is a name of an Enum value.
+type EnumMember[T comparable] struct {
+	Name  string
+	Value T
+}
+
+// NewEnum returns the Enum of members and registers it with Register, so T can be used in
+// Parse, ToString and conf settings, which is why T must be a defined type like
+// `type Level int` and not a predeclared one like int. Several members may have the same
+// value: the first one gives its canonical name and the next ones are aliases.
+//
+//	var Levels = parse.NewEnum([]parse.EnumMember[Level]{
+//		{Name: "debug", Value: Debug}, {Name: "info", Value: Info}, {Name: "warn", Value: Warn}, {Name: "warning", Value: Warn},
+//	})
+func NewEnum[T comparable](members []EnumMember[T]) *Enum[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.PkgPath() == "" {
+		panic(fmt.Sprintf("parse: enum of the predeclared or unnamed type %s", t))
+	}
+	e := &Enum[T]{values: map[string]T{}, canonical: map[T]string{}}
+	for _, member := range members {
+		key := strings.ToLower(member.Name)
+		if _, ok := e.values[key]; ok {
+			panic(fmt.Sprintf(`parse: enum %s name "%s" is repeated ignoring case`, t, member.Name))
+		}
+		e.values[key] = member.Value
+		if _, ok := e.canonical[member.Value]; !ok {
+			e.canonical[member.Value] = member.Name
+			e.names = append(e.names, member.Name)
+		}
+	}
+	register(e.Parse, e.Format, e.Names())
+	return e
+}
+
+func (e *Enum[T]) Parse(str string) (T, error) {
+	if v, ok := e.values[strings.ToLower(strings.TrimSpace(str))]; ok {
+		return v, nil
+	}
+	var zero T
+	return zero, fmt.Errorf(`invalid value "%s", expected one of %s`, str, strings.Join(e.names, ", "))
+}
+
+// Format returns the canonical name of v, or v formatted with fmt.Sprint if it has none.
+func (e *Enum[T]) Format(v T) string {
+	if name, ok := e.canonical[v]; ok {
+		return name
+	}
+	return fmt.Sprint(v)
+}
+
+// Names returns the canonical names in the order of the members, without the aliases.
+func (e *Enum[T]) Names() []string {
+	return append([]string{}, e.names...)
+}
+
+// EnumNames returns the canonical names of the Enum of type t, or nil if t has none.
+func EnumNames(t reflect.Type) []string {
+	c, ok := registered(t)
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), c.names...)
+}
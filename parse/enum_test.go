@@ -0,0 +1,42 @@
+package parse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type severity int
+
+type level int
+
+var severities = NewEnum([]EnumMember[severity]{{"debug", -4}, {"info", 0}, {"warn", 4}, {"warning", 4}, {"error", 8}})
+
+func TestEnum(t *testing.T) {
+	if names := strings.Join(severities.Names(), ","); names != "debug,info,warn,error" {
+		t.Errorf("got %s", names)
+	}
+	if v, err := severities.Parse(" WARNING "); err != nil || v != 4 || severities.Format(v) != "warn" {
+		t.Errorf("got %v %v", v, err)
+	}
+	if _, err := severities.Parse("trace"); err == nil || err.Error() != `invalid value "trace", expected one of debug, info, warn, error` {
+		t.Errorf("got %v", err)
+	}
+	levels := NewEnum([]EnumMember[level]{{"error", 2}, {"err", 2}, {"info", 1}})
+	if v, err := levels.Parse("ERR"); err != nil || levels.Format(v) != "error" || strings.Join(levels.Names(), ",") != "error,info" {
+		t.Errorf("got %v %v %v", levels.Format(v), levels.Names(), err)
+	}
+	var muted []severity
+	if err := Parse(&muted, "Debug,INFO"); err != nil || ToString(muted) != "debug,info" {
+		t.Errorf("got %v %v", muted, err)
+	}
+	if names := EnumNames(reflect.TypeOf(severity(0))); len(names) != 4 || EnumNames(reflect.TypeOf(0)) != nil {
+		t.Errorf("got %v", names)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a predeclared type")
+		}
+	}()
+	NewEnum([]EnumMember[int]{{"one", 1}})
+}
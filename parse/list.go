@@ -0,0 +1,91 @@
+package parse
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// SplitList splits a list of values separated by sep. Values are trimmed and empty ones
+// skipped unless quoted: as in CSV, a value in double quotes may contain sep, spaces and
+// quotes written twice (""). A backslash escapes a following sep, quote or backslash, both
+// inside and outside quotes, and is kept as it is before any other character, so values
+// like C:\dir or ^\d+$ need no escaping.
+func SplitList(str string, sep rune) ([]string, error) {
+	parts := []string{}
+	var sb strings.Builder
+	// keep is the length of the value that is not trimmed because it was quoted or escaped
+	keep, quoted, empty, inQuotes := 0, false, true, false
+	flush := func() {
+		part := sb.String()
+		end := len(strings.TrimRightFunc(part, unicode.IsSpace))
+		if end < keep {
+			end = keep
+		}
+		if part = part[:end]; quoted || part != "" {
+			parts = append(parts, part)
+		}
+		sb.Reset()
+		keep, quoted, empty = 0, false, true
+	}
+	runes := []rune(str)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inQuotes && r == '"' && i+1 < len(runes) && runes[i+1] == '"':
+			i++
+			sb.WriteRune('"')
+			keep = sb.Len()
+		case inQuotes && r == '"':
+			inQuotes = false
+		case r == '\\' && i+1 < len(runes) && (runes[i+1] == sep || runes[i+1] == '"' || runes[i+1] == '\\'):
+			i++
+			sb.WriteRune(runes[i])
+			keep, empty = sb.Len(), false
+		case inQuotes:
+			sb.WriteRune(r)
+			keep = sb.Len()
+		case r == sep:
+			flush()
+		case r == '"' && empty:
+			inQuotes, quoted, empty = true, true, false
+		case unicode.IsSpace(r) && empty:
+		default:
+			sb.WriteRune(r)
+			empty = false
+		}
+	}
+	if inQuotes {
+		return nil, errors.New("unterminated quoted value")
+	}
+	flush()
+	return parts, nil
+}
+
+// JoinList joins the values with sep, quoting those that SplitList would not read back
+// as they are.
+func JoinList(parts []string, sep rune) string {
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		if part == "" || strings.ContainsAny(part, string(sep)+`"\`) || strings.TrimSpace(part) != part {
+			part = `"` + strings.NewReplacer(`\`, `\\`, `"`, `""`).Replace(part) + `"`
+		}
+		quoted[i] = part
+	}
+	return strings.Join(quoted, string(sep))
+}
+
+// listSeparator is ";" for lists of unnamed slices, which are lists themselves, and ","
+// otherwise.
+func listSeparator(t reflect.Type) rune {
+	if isList(t.Elem()) {
+		return ';'
+	}
+	return ','
+}
+
+// isList reports whether t is an unnamed slice, formatted as a list by Codec.
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Name() == ""
+}
@@ -0,0 +1,63 @@
+package parse
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/enolgor/go-utils/parse/types"
+)
+
+func TestSplitList(t *testing.T) {
+	cases := map[string][]string{
+		`a, b ,,c`:             {"a", "b", "c"},
+		`"x, y",z`:             {"x, y", "z"},
+		`"say ""hi""", ""`:     {`say "hi"`, ""},
+		`a\,b,c\\,\ d\ `:       {"a,b", `c\`, `\ d\`},
+		`"\"quoted\"", " sp "`: {`"quoted"`, " sp "},
+		`C:\dir\file,D:\`:      {`C:\dir\file`, `D:\`},
+	}
+	for str, expected := range cases {
+		parts, err := SplitList(str, ',')
+		if err != nil || strings.Join(parts, "|") != strings.Join(expected, "|") || len(parts) != len(expected) {
+			t.Errorf("%s: got %q %v", str, parts, err)
+		}
+		again, _ := SplitList(JoinList(parts, ','), ',')
+		if strings.Join(again, "|") != strings.Join(expected, "|") || len(again) != len(expected) {
+			t.Errorf("%s: %s does not round-trip", str, JoinList(parts, ','))
+		}
+	}
+	if _, err := SplitList(`"open`, ','); err == nil {
+		t.Error("expected unterminated quote error")
+	}
+	names := []string{"Doe, Jane", "Smith"}
+	if ToString(names) != `"Doe, Jane",Smith` {
+		t.Errorf("got %s", ToString(names))
+	}
+	var patterns []*regexp.Regexp
+	if err := Parse(&patterns, `^\d+$,\w\,\s`); err != nil || len(patterns) != 2 || patterns[0].String() != `^\d+$` || patterns[1].String() != `\w,\s` {
+		t.Errorf("got %v %v", patterns, err)
+	}
+	var nested [][]string
+	if err := Parse(&nested, `a\,b,c;d`); err != nil || len(nested) != 2 || len(nested[0]) != 2 || nested[0][0] != "a,b" || nested[1][0] != "d" {
+		t.Errorf("got %q %v", nested, err)
+	}
+	var again [][]string
+	if err := Parse(&again, ToString(nested)); err != nil || ToString(again) != ToString(nested) || again[0][0] != "a,b" {
+		t.Errorf("%s does not round-trip: %q %v", ToString(nested), again, err)
+	}
+}
+
+func TestNestedLists(t *testing.T) {
+	var matrix [][]int
+	if err := Parse(&matrix, "1,2;3"); err != nil || len(matrix) != 2 || len(matrix[0]) != 2 || ToString(matrix) != "1,2;3" {
+		t.Errorf("got %v %v", matrix, err)
+	}
+	if _, _, ok := Codec(reflect.TypeOf([][][]int{})); ok {
+		t.Error("expected lists nested three levels to be unsupported")
+	}
+	if _, _, ok := Codec(reflect.TypeOf([][]types.HexBytes{})); !ok {
+		t.Error("expected lists of lists of a named slice to be supported")
+	}
+}
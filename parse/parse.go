@@ -4,8 +4,14 @@ import (
 	"encoding/base32"
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/enolgor/go-utils/parse/types"
@@ -19,16 +25,22 @@ type Parseable interface {
 		bool | string |
 		complex64 | complex128 |
 		time.Duration | time.Time | time.Location | language.Tag |
+		*url.URL | netip.Addr | netip.Prefix | netip.AddrPort |
+		*regexp.Regexp | os.FileMode | mail.Address |
 		[]int | []int8 | []int16 | []int32 | []int64 |
 		[]uint | []uint8 | []uint16 | []uint32 | []uint64 |
 		[]float32 | []float64 |
 		[]bool | []string |
 		[]complex64 | []complex128 |
 		[]time.Duration | []time.Time | []time.Location | []language.Tag |
+		[]*url.URL | []netip.Addr | []netip.Prefix | []netip.AddrPort |
+		[]*regexp.Regexp | []os.FileMode | []mail.Address |
 		types.HexByte | types.OctByte |
 		types.HexBytes | types.B32Bytes | types.B64Bytes |
+		types.ByteSize | types.Rate |
 		[]types.HexByte | []types.OctByte |
-		[]types.HexBytes | []types.B32Bytes | []types.B64Bytes
+		[]types.HexBytes | []types.B32Bytes | []types.B64Bytes |
+		[]types.ByteSize | []types.Rate
 }
 
 func Int(str string) (int, error) {
@@ -168,16 +180,19 @@ func Complex128ToString(v complex128) string {
 	return strconv.FormatComplex(v, 'f', -1, 128)
 }
 
+// Duration parses str with DefaultDurationParser.
 func Duration(str string) (time.Duration, error) {
-	return time.ParseDuration(str)
+	return DefaultDurationParser.Parse(str)
 }
 
+// DurationToString formats v with DefaultDurationParser.
 func DurationToString(v time.Duration) string {
-	return v.String()
+	return DefaultDurationParser.Format(v)
 }
 
+// Time parses str with DefaultTimeParser.
 func Time(str string) (time.Time, error) {
-	return time.Parse(time.RFC3339, str)
+	return DefaultTimeParser.Parse(str)
 }
 
 func TimeToString(v time.Time) string {
@@ -204,6 +219,102 @@ func LanguageToString(v language.Tag) string {
 	return v.String()
 }
 
+func URL(str string) (*url.URL, error) {
+	return url.Parse(str)
+}
+
+func URLToString(v *url.URL) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// Addr, Prefix and AddrPort parse "" as the zero value, which their formatters write as ""
+// instead of "invalid IP", "invalid Prefix" and "invalid AddrPort".
+
+func Addr(str string) (netip.Addr, error) {
+	if str == "" {
+		return netip.Addr{}, nil
+	}
+	return netip.ParseAddr(str)
+}
+
+func AddrToString(v netip.Addr) string {
+	if !v.IsValid() {
+		return ""
+	}
+	return v.String()
+}
+
+func Prefix(str string) (netip.Prefix, error) {
+	if str == "" {
+		return netip.Prefix{}, nil
+	}
+	return netip.ParsePrefix(str)
+}
+
+func PrefixToString(v netip.Prefix) string {
+	if !v.IsValid() {
+		return ""
+	}
+	return v.String()
+}
+
+func AddrPort(str string) (netip.AddrPort, error) {
+	if str == "" {
+		return netip.AddrPort{}, nil
+	}
+	return netip.ParseAddrPort(str)
+}
+
+func AddrPortToString(v netip.AddrPort) string {
+	if !v.IsValid() {
+		return ""
+	}
+	return v.String()
+}
+
+func Regexp(str string) (*regexp.Regexp, error) {
+	return regexp.Compile(str)
+}
+
+func RegexpToString(v *regexp.Regexp) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// FileMode parses the permission bits in octal, such as 0644.
+func FileMode(str string) (os.FileMode, error) {
+	v, err := strconv.ParseUint(str, 8, 32)
+	return os.FileMode(v), err
+}
+
+func FileModeToString(v os.FileMode) string {
+	return fmt.Sprintf("%#o", uint32(v))
+}
+
+// MailAddress parses "" as the zero value, which MailAddressToString writes as "".
+func MailAddress(str string) (mail.Address, error) {
+	if str == "" {
+		return mail.Address{}, nil
+	}
+	v, err := mail.ParseAddress(str)
+	if err != nil {
+		return mail.Address{}, err
+	}
+	return *v, nil
+}
+
+func MailAddressToString(v mail.Address) string {
+	if v == (mail.Address{}) {
+		return ""
+	}
+	return v.String()
+}
+
 func HexByte(str string) (types.HexByte, error) {
 	v, err := strconv.ParseUint(str, 16, 8)
 	return types.HexByte(v), err
@@ -249,7 +360,7 @@ func B64BytesToString(v types.B64Bytes) string {
 	return base64.StdEncoding.EncodeToString([]byte(v))
 }
 
-func Must[P Parseable](parser func(string) (P, error)) func(string) P {
+func Must[P any](parser func(string) (P, error)) func(string) P {
 	return func(str string) P {
 		v, err := parser(str)
 		if err != nil {
@@ -259,9 +370,34 @@ func Must[P Parseable](parser func(string) (P, error)) func(string) P {
 	}
 }
 
-func GetParser[P Parseable](take *P) func(string) (P, error) {
+// GetParser returns the parser of the type of take: the one registered with Register,
+// a built-in Parseable parser or, as in Codec, one based on encoding.TextUnmarshaler.
+// The parser of an unsupported type always fails.
+func GetParser[P any](take *P) func(string) (P, error) {
+	t := reflect.TypeOf(take).Elem()
+	if _, ok := registered(t); !ok {
+		if p := builtinParser(take); p != nil {
+			return p.(func(string) (P, error))
+		}
+	}
+	parser, _, ok := Codec(t)
+	return func(str string) (P, error) {
+		var v P
+		if !ok {
+			return v, fmt.Errorf("unsupported type %s", t)
+		}
+		parsed, err := parser(str)
+		if err != nil {
+			return v, err
+		}
+		return parsed.(P), nil
+	}
+}
+
+// builtinParser returns the parser of the Parseable type pointed by take, or nil.
+func builtinParser(take any) any {
 	var p any
-	switch any(take).(type) {
+	switch take.(type) {
 	case *int:
 		p = any(Int)
 	case *int8:
@@ -302,6 +438,20 @@ func GetParser[P Parseable](take *P) func(string) (P, error) {
 		p = any(Location)
 	case *language.Tag:
 		p = any(Language)
+	case **url.URL:
+		p = any(URL)
+	case *netip.Addr:
+		p = any(Addr)
+	case *netip.Prefix:
+		p = any(Prefix)
+	case *netip.AddrPort:
+		p = any(AddrPort)
+	case **regexp.Regexp:
+		p = any(Regexp)
+	case *os.FileMode:
+		p = any(FileMode)
+	case *mail.Address:
+		p = any(MailAddress)
 	case *[]int:
 		p = any(ParseArray(Int))
 	case *[]int8:
@@ -342,6 +492,20 @@ func GetParser[P Parseable](take *P) func(string) (P, error) {
 		p = any(ParseArray(Location))
 	case *[]language.Tag:
 		p = any(ParseArray(Language))
+	case *[]*url.URL:
+		p = any(ParseArray(URL))
+	case *[]netip.Addr:
+		p = any(ParseArray(Addr))
+	case *[]netip.Prefix:
+		p = any(ParseArray(Prefix))
+	case *[]netip.AddrPort:
+		p = any(ParseArray(AddrPort))
+	case *[]*regexp.Regexp:
+		p = any(ParseArray(Regexp))
+	case *[]os.FileMode:
+		p = any(ParseArray(FileMode))
+	case *[]mail.Address:
+		p = any(ParseArray(MailAddress))
 	case *types.HexByte:
 		p = any(HexByte)
 	case *types.OctByte:
@@ -352,6 +516,10 @@ func GetParser[P Parseable](take *P) func(string) (P, error) {
 		p = any(B32Bytes)
 	case *types.B64Bytes:
 		p = any(B64Bytes)
+	case *types.ByteSize:
+		p = any(ByteSize)
+	case *types.Rate:
+		p = any(Rate)
 	case *[]types.HexByte:
 		p = any(ParseArray(HexByte))
 	case *[]types.OctByte:
@@ -362,158 +530,222 @@ func GetParser[P Parseable](take *P) func(string) (P, error) {
 		p = any(ParseArray(B32Bytes))
 	case *[]types.B64Bytes:
 		p = any(ParseArray(B64Bytes))
+	case *[]types.ByteSize:
+		p = any(ParseArray(ByteSize))
+	case *[]types.Rate:
+		p = any(ParseArray(Rate))
 	}
-	return p.(func(string) (P, error))
+	return p
 }
 
-func Parse[P Parseable](take *P, str string) error {
+func Parse[P any](take *P, str string) error {
 	parse := GetParser(take)
 	var err error
 	*take, err = parse(str)
 	return err
 }
 
-func MustParse[P Parseable](take *P, str string) {
+func MustParse[P any](take *P, str string) {
 	if err := Parse(take, str); err != nil {
 		panic(err)
 	}
 }
 
-func ParseArray[P Parseable](parser func(string) (P, error)) func(string) ([]P, error) {
+func ParseArray[P any](parser func(string) (P, error)) func(string) ([]P, error) {
+	return ParseArraySep(parser, ',')
+}
+
+// ParseArraySep is like ParseArray with the given separator, so that lists can be nested:
+// ParseArraySep(ParseArray(Int), ';') parses "1,2;3,4" as [][]int{{1, 2}, {3, 4}}.
+func ParseArraySep[P any](parser func(string) (P, error), sep rune) func(string) ([]P, error) {
 	return func(str string) ([]P, error) {
-		ret := []P{}
-		var part string
-		var v P
-		var err error
-		parts := strings.Split(str, ",")
+		parts, err := SplitList(str, sep)
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]P, len(parts))
 		for i := range parts {
-			if part = strings.TrimSpace(parts[i]); part != "" {
-				if v, err = parser(part); err != nil {
-					return nil, err
-				}
-				ret = append(ret, v)
+			if ret[i], err = parser(parts[i]); err != nil {
+				return nil, err
 			}
 		}
 		return ret, nil
 	}
 }
 
-func ArrayToString[P Parseable](encoder func(P) string) func([]P) string {
+func ArrayToString[P any](encoder func(P) string) func([]P) string {
+	return ArrayToStringSep(encoder, ',')
+}
+
+func ArrayToStringSep[P any](encoder func(P) string, sep rune) func([]P) string {
 	return func(v []P) string {
-		if len(v) == 0 {
-			return ""
+		parts := make([]string, len(v))
+		for i := range v {
+			parts[i] = encoder(v[i])
 		}
-		ret := ""
-		for i := 0; i < len(v)-1; i++ {
-			ret = ret + encoder(v[i]) + ","
+		return JoinList(parts, sep)
+	}
+}
+
+// ToString formats v with the formatter of its type, falling back to fmt.Sprint for
+// unsupported types.
+func ToString[P any](v P) string {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return ""
+	}
+	if _, ok := registered(t); !ok {
+		if str, ok := builtinToString(v); ok {
+			return str
 		}
-		ret = ret + encoder(v[len(v)-1])
-		return ret
 	}
+	if _, formatter, ok := Codec(t); ok {
+		return formatter(v)
+	}
+	return fmt.Sprint(v)
 }
 
-func ToString[P Parseable](v P) string {
-	switch p := any(v).(type) {
+func builtinToString(v any) (string, bool) {
+	var str string
+	switch p := v.(type) {
 	case int:
-		return IntToString(p)
+		str = IntToString(p)
 	case int8:
-		return Int8ToString(p)
+		str = Int8ToString(p)
 	case int16:
-		return Int16ToString(p)
+		str = Int16ToString(p)
 	case int32:
-		return Int32ToString(p)
+		str = Int32ToString(p)
 	case int64:
-		return Int64ToString(p)
+		str = Int64ToString(p)
 	case uint:
-		return UintToString(p)
+		str = UintToString(p)
 	case uint8:
-		return Uint8ToString(p)
+		str = Uint8ToString(p)
 	case uint16:
-		return Uint16ToString(p)
+		str = Uint16ToString(p)
 	case uint32:
-		return Uint32ToString(p)
+		str = Uint32ToString(p)
 	case uint64:
-		return Uint64ToString(p)
+		str = Uint64ToString(p)
 	case float32:
-		return Float32ToString(p)
+		str = Float32ToString(p)
 	case float64:
-		return Float64ToString(p)
+		str = Float64ToString(p)
 	case bool:
-		return BoolToString(p)
+		str = BoolToString(p)
 	case string:
-		return StringToString(p)
+		str = StringToString(p)
 	case complex64:
-		return Complex64ToString(p)
+		str = Complex64ToString(p)
 	case complex128:
-		return Complex128ToString(p)
+		str = Complex128ToString(p)
 	case time.Duration:
-		return DurationToString(p)
+		str = DurationToString(p)
 	case time.Time:
-		return TimeToString(p)
+		str = TimeToString(p)
 	case time.Location:
-		return LocationToString(p)
+		str = LocationToString(p)
 	case language.Tag:
-		return LanguageToString(p)
+		str = LanguageToString(p)
+	case *url.URL:
+		str = URLToString(p)
+	case netip.Addr:
+		str = AddrToString(p)
+	case netip.Prefix:
+		str = PrefixToString(p)
+	case netip.AddrPort:
+		str = AddrPortToString(p)
+	case *regexp.Regexp:
+		str = RegexpToString(p)
+	case os.FileMode:
+		str = FileModeToString(p)
+	case mail.Address:
+		str = MailAddressToString(p)
 	case []int:
-		return ArrayToString(IntToString)(p)
+		str = ArrayToString(IntToString)(p)
 	case []int8:
-		return ArrayToString(Int8ToString)(p)
+		str = ArrayToString(Int8ToString)(p)
 	case []int16:
-		return ArrayToString(Int16ToString)(p)
+		str = ArrayToString(Int16ToString)(p)
 	case []int32:
-		return ArrayToString(Int32ToString)(p)
+		str = ArrayToString(Int32ToString)(p)
 	case []int64:
-		return ArrayToString(Int64ToString)(p)
+		str = ArrayToString(Int64ToString)(p)
 	case []uint:
-		return ArrayToString(UintToString)(p)
+		str = ArrayToString(UintToString)(p)
 	case []uint8:
-		return ArrayToString(Uint8ToString)(p)
+		str = ArrayToString(Uint8ToString)(p)
 	case []uint16:
-		return ArrayToString(Uint16ToString)(p)
+		str = ArrayToString(Uint16ToString)(p)
 	case []uint32:
-		return ArrayToString(Uint32ToString)(p)
+		str = ArrayToString(Uint32ToString)(p)
 	case []uint64:
-		return ArrayToString(Uint64ToString)(p)
+		str = ArrayToString(Uint64ToString)(p)
 	case []float32:
-		return ArrayToString(Float32ToString)(p)
+		str = ArrayToString(Float32ToString)(p)
 	case []float64:
-		return ArrayToString(Float64ToString)(p)
+		str = ArrayToString(Float64ToString)(p)
 	case []bool:
-		return ArrayToString(BoolToString)(p)
+		str = ArrayToString(BoolToString)(p)
 	case []string:
-		return ArrayToString(StringToString)(p)
+		str = ArrayToString(StringToString)(p)
 	case []complex64:
-		return ArrayToString(Complex64ToString)(p)
+		str = ArrayToString(Complex64ToString)(p)
 	case []complex128:
-		return ArrayToString(Complex128ToString)(p)
+		str = ArrayToString(Complex128ToString)(p)
 	case []time.Duration:
-		return ArrayToString(DurationToString)(p)
+		str = ArrayToString(DurationToString)(p)
 	case []time.Time:
-		return ArrayToString(TimeToString)(p)
+		str = ArrayToString(TimeToString)(p)
 	case []time.Location:
-		return ArrayToString(LocationToString)(p)
+		str = ArrayToString(LocationToString)(p)
 	case []language.Tag:
-		return ArrayToString(LanguageToString)(p)
+		str = ArrayToString(LanguageToString)(p)
+	case []*url.URL:
+		str = ArrayToString(URLToString)(p)
+	case []netip.Addr:
+		str = ArrayToString(AddrToString)(p)
+	case []netip.Prefix:
+		str = ArrayToString(PrefixToString)(p)
+	case []netip.AddrPort:
+		str = ArrayToString(AddrPortToString)(p)
+	case []*regexp.Regexp:
+		str = ArrayToString(RegexpToString)(p)
+	case []os.FileMode:
+		str = ArrayToString(FileModeToString)(p)
+	case []mail.Address:
+		str = ArrayToString(MailAddressToString)(p)
 	case types.HexByte:
-		return HexByteToString(p)
+		str = HexByteToString(p)
 	case types.OctByte:
-		return OctByteToString(p)
+		str = OctByteToString(p)
 	case types.HexBytes:
-		return HexBytesToString(p)
+		str = HexBytesToString(p)
 	case types.B32Bytes:
-		return B32BytesToString(p)
+		str = B32BytesToString(p)
 	case types.B64Bytes:
-		return B64BytesToString(p)
+		str = B64BytesToString(p)
+	case types.ByteSize:
+		str = ByteSizeToString(p)
+	case types.Rate:
+		str = RateToString(p)
 	case []types.HexByte:
-		return ArrayToString(HexByteToString)(p)
+		str = ArrayToString(HexByteToString)(p)
 	case []types.OctByte:
-		return ArrayToString(OctByteToString)(p)
+		str = ArrayToString(OctByteToString)(p)
 	case []types.HexBytes:
-		return ArrayToString(HexBytesToString)(p)
+		str = ArrayToString(HexBytesToString)(p)
 	case []types.B32Bytes:
-		return ArrayToString(B32BytesToString)(p)
+		str = ArrayToString(B32BytesToString)(p)
 	case []types.B64Bytes:
-		return ArrayToString(B64BytesToString)(p)
+		str = ArrayToString(B64BytesToString)(p)
+	case []types.ByteSize:
+		str = ArrayToString(ByteSizeToString)(p)
+	case []types.Rate:
+		str = ArrayToString(RateToString)(p)
+	default:
+		return "", false
 	}
-	panic("should not reach")
+	return str, true
 }
@@ -0,0 +1,43 @@
+package parse
+
+import (
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func roundTrip[T comparable](t *testing.T, str string, expected T) {
+	t.Helper()
+	var v T
+	if err := Parse(&v, str); err != nil || v != expected {
+		t.Errorf("%s: got %v %v, wanted %v", str, v, err, expected)
+	}
+	if ToString(v) != str {
+		t.Errorf("%s: formatted as %s", str, ToString(v))
+	}
+}
+
+func TestNetworkTypes(t *testing.T) {
+	roundTrip(t, "192.168.1.1", netip.MustParseAddr("192.168.1.1"))
+	roundTrip(t, "", netip.Addr{})
+	roundTrip(t, "fd00::/8", netip.MustParsePrefix("fd00::/8"))
+	roundTrip(t, "", netip.Prefix{})
+	roundTrip(t, "[::1]:8080", netip.MustParseAddrPort("[::1]:8080"))
+	roundTrip(t, "", netip.AddrPort{})
+	roundTrip(t, `"Jane Doe" <jane@example.com>`, mail.Address{Name: "Jane Doe", Address: "jane@example.com"})
+	roundTrip(t, "", mail.Address{})
+	roundTrip(t, "0640", os.FileMode(0640))
+	var ip netip.Addr
+	if err := Parse(&ip, "999.1.1.1"); err == nil {
+		t.Error("expected invalid ip error")
+	}
+	var endpoint *url.URL
+	if err := Parse(&endpoint, "https://example.com/api?x=1"); err != nil || endpoint.Host != "example.com" || ToString(endpoint) != "https://example.com/api?x=1" {
+		t.Errorf("got %v %v", endpoint, err)
+	}
+	if ToString((*url.URL)(nil)) != "" {
+		t.Error("expected a nil URL to be formatted as an empty string")
+	}
+}
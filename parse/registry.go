@@ -0,0 +1,153 @@
+package parse
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type codec struct {
+	parser    func(string) (any, error)
+	formatter func(any) string
+	names     []string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[reflect.Type]codec{}
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Register sets the parser and formatter used for the values of type T by Parse, GetParser,
+// ToString and Codec, taking precedence over the built-in ones. Slices of T are supported
+// as lists like in ParseArray.
+func Register[T any](parser func(string) (T, error), formatter func(T) string) {
+	register(parser, formatter, nil)
+}
+
+func register[T any](parser func(string) (T, error), formatter func(T) string, names []string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[reflect.TypeOf((*T)(nil)).Elem()] = codec{
+		parser: func(str string) (any, error) {
+			return parser(str)
+		},
+		formatter: func(v any) string {
+			return formatter(v.(T))
+		},
+		names: names,
+	}
+}
+
+func registered(t reflect.Type) (codec, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := registry[t]
+	return c, ok
+}
+
+// Codec returns the parser and formatter of the values of type t, in order of preference
+// the ones registered with Register, the built-in ones of the Parseable types or the
+// encoding.TextUnmarshaler and encoding.TextMarshaler (or fmt.Sprint) implementations
+// of t. Slices of a supported type are lists as in ParseArray, and slices of slices lists
+// separated by ";" of those lists. Deeper lists have no separator left, so ok is false
+// for them as for any other type that is not supported.
+func Codec(t reflect.Type) (parser func(string) (any, error), formatter func(any) string, ok bool) {
+	if c, ok := registered(t); ok {
+		return c.parser, c.formatter, true
+	}
+	if p := builtinParser(reflect.New(t).Interface()); p != nil {
+		fn := reflect.ValueOf(p)
+		parser = func(str string) (any, error) {
+			out := fn.Call([]reflect.Value{reflect.ValueOf(str)})
+			err, _ := out[1].Interface().(error)
+			return out[0].Interface(), err
+		}
+		formatter = func(v any) string {
+			str, _ := builtinToString(v)
+			return str
+		}
+		return parser, formatter, true
+	}
+	if parser, ok := textParser(t); ok {
+		return parser, textFormatter(t), true
+	}
+	if t.Kind() == reflect.Slice && !(isList(t.Elem()) && isList(t.Elem().Elem())) {
+		if elemParser, elemFormatter, ok := Codec(t.Elem()); ok {
+			return sliceParser(t, elemParser), sliceFormatter(t, elemFormatter), true
+		}
+	}
+	return nil, nil, false
+}
+
+func textParser(t reflect.Type) (func(string) (any, error), bool) {
+	switch {
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return func(str string) (any, error) {
+			v := reflect.New(t)
+			err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+			return v.Elem().Interface(), err
+		}, true
+	case t.Kind() == reflect.Pointer && t.Implements(textUnmarshalerType):
+		return func(str string) (any, error) {
+			v := reflect.New(t.Elem())
+			err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+			return v.Interface(), err
+		}, true
+	}
+	return nil, false
+}
+
+func textFormatter(t reflect.Type) func(any) string {
+	return func(v any) string {
+		rv := reflect.ValueOf(v)
+		if t.Kind() == reflect.Pointer && rv.IsNil() {
+			return ""
+		}
+		if !t.Implements(textMarshalerType) && reflect.PointerTo(t).Implements(textMarshalerType) {
+			ptr := reflect.New(t)
+			ptr.Elem().Set(rv)
+			rv = ptr
+		}
+		if m, ok := rv.Interface().(encoding.TextMarshaler); ok {
+			if text, err := m.MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+func sliceParser(t reflect.Type, parser func(string) (any, error)) func(string) (any, error) {
+	sep := listSeparator(t)
+	return func(str string) (any, error) {
+		parts, err := SplitList(str, sep)
+		if err != nil {
+			return nil, err
+		}
+		ret := reflect.MakeSlice(t, 0, len(parts))
+		for _, part := range parts {
+			v, err := parser(part)
+			if err != nil {
+				return nil, err
+			}
+			ret = reflect.Append(ret, reflect.ValueOf(v))
+		}
+		return ret.Interface(), nil
+	}
+}
+
+func sliceFormatter(t reflect.Type, formatter func(any) string) func(any) string {
+	sep := listSeparator(t)
+	return func(v any) string {
+		rv := reflect.ValueOf(v)
+		parts := make([]string, rv.Len())
+		for i := range parts {
+			parts[i] = formatter(rv.Index(i).Interface())
+		}
+		return JoinList(parts, sep)
+	}
+}
@@ -0,0 +1,68 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type celsius float64
+
+type point struct {
+	X, Y int
+}
+
+func (p *point) UnmarshalText(text []byte) error {
+	x, y, ok := strings.Cut(string(text), " ")
+	if !ok {
+		return errors.New("missing coordinate")
+	}
+	if err := Parse(&p.X, x); err != nil {
+		return err
+	}
+	return Parse(&p.Y, y)
+}
+
+func (p point) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d %d", p.X, p.Y)), nil
+}
+
+func TestRegister(t *testing.T) {
+	Register(func(str string) (celsius, error) {
+		f, err := Float64(strings.TrimSuffix(str, "C"))
+		return celsius(f), err
+	}, func(v celsius) string {
+		return Float64ToString(float64(v)) + "C"
+	})
+	var temp celsius
+	if err := Parse(&temp, "21.5C"); err != nil || temp != 21.5 || ToString(temp) != "21.5C" {
+		t.Errorf("got %v %v", temp, err)
+	}
+	var temps []celsius
+	if err := Parse(&temps, "1C,2C"); err != nil || len(temps) != 2 || ToString(temps) != "1C,2C" {
+		t.Errorf("got %v %v", temps, err)
+	}
+	if err := Parse(&temp, "warm"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestCodec(t *testing.T) {
+	parser, formatter, ok := Codec(reflect.TypeOf(point{}))
+	if !ok {
+		t.Fatal("expected TextUnmarshaler types to be supported")
+	}
+	p, err := parser("1 2")
+	if err != nil || p != (point{1, 2}) || formatter(p) != "1 2" {
+		t.Errorf("got %v %v", p, err)
+	}
+	var points []point
+	if err := Parse(&points, "1 2,3 4"); err != nil || len(points) != 2 || ToString(points) != "1 2,3 4" {
+		t.Errorf("got %v %v", points, err)
+	}
+	if _, _, ok := Codec(reflect.TypeOf(struct{ A int }{})); ok {
+		t.Error("expected unsupported type")
+	}
+}
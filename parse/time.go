@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeParser parses times in any of its Layouts, as Unix epochs in seconds or, if greater
+// than 1e12, milliseconds, and relative to the current time such as now, now-24h or
+// now+1h30m. Times without a zone and relative times are in Location (UTC if nil).
+type TimeParser struct {
+	Layouts  []string
+	Location *time.Location
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+// DefaultTimeParser is used by Time and so by GetParser and Parse for time.Time values.
+// Its layouts and location can be changed before parsing any value.
+var DefaultTimeParser = &TimeParser{
+	Layouts: []string{
+		time.RFC3339Nano,
+		time.RFC1123Z,
+		time.RFC1123,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		time.DateOnly,
+	},
+	Location: time.UTC,
+}
+
+func (p *TimeParser) Parse(str string) (time.Time, error) {
+	str = strings.TrimSpace(str)
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range p.Layouts {
+		if t, err := time.ParseInLocation(layout, str, loc); err == nil {
+			return t, nil
+		}
+	}
+	if epoch, err := strconv.ParseInt(str, 10, 64); err == nil {
+		if epoch > 1e12 || epoch < -1e12 {
+			return time.UnixMilli(epoch).In(loc), nil
+		}
+		return time.Unix(epoch, 0).In(loc), nil
+	}
+	if offset, ok := strings.CutPrefix(str, "now"); ok {
+		now := time.Now
+		if p.Now != nil {
+			now = p.Now
+		}
+		if offset == "" {
+			return now().In(loc), nil
+		}
+		if offset[0] == '+' || offset[0] == '-' {
+			d, err := Duration(offset[1:])
+			if err != nil {
+				return time.Time{}, fmt.Errorf(`invalid relative time "%s": %s`, str, err.Error())
+			}
+			if offset[0] == '-' {
+				d = -d
+			}
+			return now().In(loc).Add(d), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(`invalid time "%s", expected one of the layouts %s, a Unix epoch or a relative time like now-24h`, str, strings.Join(p.Layouts, ", "))
+}
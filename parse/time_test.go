@@ -0,0 +1,34 @@
+package parse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeParser(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip(err)
+	}
+	p := &TimeParser{Layouts: DefaultTimeParser.Layouts, Location: madrid, Now: func() time.Time { return now }}
+	cases := map[string]time.Time{
+		"2024-03-10T10:00:00Z":            time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC),
+		"Sun, 10 Mar 2024 10:00:00 +0000": time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC),
+		"2024-03-10":                      time.Date(2024, 3, 10, 0, 0, 0, 0, madrid),
+		"2024-03-10 08:30:00":             time.Date(2024, 3, 10, 8, 30, 0, 0, madrid),
+		"1710064800":                      time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC),
+		"1710064800500":                   time.Date(2024, 3, 10, 10, 0, 0, 5e8, time.UTC),
+		"now":                             now,
+		"now-24h":                         now.Add(-24 * time.Hour),
+		"now+1h30m":                       now.Add(90 * time.Minute),
+	}
+	for str, expected := range cases {
+		if v, err := p.Parse(str); err != nil || !v.Equal(expected) {
+			t.Errorf("%s: got %v %v, wanted %v", str, v, err, expected)
+		}
+	}
+	if _, err := p.Parse("yesterday"); err == nil {
+		t.Error("expected invalid time error")
+	}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type HexByte byte
 
 type OctByte byte
@@ -9,3 +11,12 @@ type HexBytes []byte
 type B32Bytes []byte
 
 type B64Bytes []byte
+
+// ByteSize is a number of bytes written with an SI (KB, MB...) or IEC (KiB, MiB...) unit.
+type ByteSize uint64
+
+// Rate is a number of events per period of time, written as 100/s or 5000/h.
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
@@ -0,0 +1,138 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/enolgor/go-utils/parse/types"
+)
+
+type byteUnit struct {
+	name string
+	size uint64
+}
+
+// byteUnits is sorted by decreasing size, the first name of every size being the one used
+// by ByteSizeToString.
+var byteUnits = []byteUnit{
+	{"EiB", 1 << 60}, {"EB", 1e18},
+	{"PiB", 1 << 50}, {"PB", 1e15},
+	{"TiB", 1 << 40}, {"TB", 1e12},
+	{"GiB", 1 << 30}, {"GB", 1e9},
+	{"MiB", 1 << 20}, {"MB", 1e6},
+	{"KiB", 1 << 10}, {"KB", 1e3},
+	{"B", 1},
+}
+
+func byteUnitSize(unit string) (uint64, bool) {
+	unit = strings.ToLower(unit)
+	if unit == "" {
+		return 1, true
+	}
+	for _, u := range byteUnits {
+		name := strings.ToLower(u.name)
+		// 512k, 512Ki and 512KiB are all accepted
+		if unit == name || unit == strings.TrimSuffix(name, "b") {
+			return u.size, true
+		}
+	}
+	return 0, false
+}
+
+// ByteSize parses sizes such as 512, 512k, 10MB or 1.5GiB. SI units (KB, MB, GB...) are
+// powers of 1000 and IEC units (KiB, MiB, GiB...) powers of 1024, case insensitive. Sizes
+// in larger units are rounded to whole bytes, while a fractional number of bytes is an error.
+func ByteSize(str string) (types.ByteSize, error) {
+	str = strings.TrimSpace(str)
+	i := strings.IndexFunc(str, unicode.IsLetter)
+	if i < 0 {
+		i = len(str)
+	}
+	number, unit := strings.TrimSpace(str[:i]), str[i:]
+	size, ok := byteUnitSize(unit)
+	if !ok {
+		return 0, fmt.Errorf(`unknown byte size unit "%s"`, unit)
+	}
+	if v, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if v > math.MaxUint64/size {
+			return 0, errors.New("byte size out of range")
+		}
+		return types.ByteSize(v * size), nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf(`invalid byte size "%s"`, str)
+	}
+	if size == 1 && f != math.Trunc(f) {
+		return 0, fmt.Errorf(`fractional byte size "%s"`, str)
+	}
+	if f < 0 || f*float64(size) >= math.MaxUint64 {
+		return 0, errors.New("byte size out of range")
+	}
+	return types.ByteSize(math.Round(f * float64(size))), nil
+}
+
+// ByteSizeToString uses the largest unit giving a number with at most 3 decimals that
+// parses back to v, such as 1.5GiB or 10MB.
+func ByteSizeToString(v types.ByteSize) string {
+	for _, u := range byteUnits {
+		if uint64(v) < u.size {
+			continue
+		}
+		number := strconv.FormatFloat(float64(v)/float64(u.size), 'f', -1, 64)
+		if _, decimals, _ := strings.Cut(number, "."); len(decimals) > 3 {
+			continue
+		}
+		if parsed, err := ByteSize(number + u.name); err == nil && parsed == v {
+			return number + u.name
+		}
+	}
+	return strconv.FormatUint(uint64(v), 10) + "B"
+}
+
+var ratePeriods = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+}
+
+// Rate parses rates such as 100/s, 5000/h or 10/30s. The count cannot be negative and the
+// period is a unit among ms, s, m, h and d or a duration.
+func Rate(str string) (types.Rate, error) {
+	count, per, ok := strings.Cut(strings.TrimSpace(str), "/")
+	if !ok {
+		return types.Rate{}, fmt.Errorf(`missing "/" in rate "%s"`, str)
+	}
+	var rate types.Rate
+	var err error
+	if rate.Count, err = strconv.Atoi(strings.TrimSpace(count)); err != nil || rate.Count < 0 {
+		return types.Rate{}, fmt.Errorf(`invalid rate count "%s"`, count)
+	}
+	per = strings.TrimSpace(per)
+	if period, ok := ratePeriods[per]; ok {
+		rate.Per = period
+	} else if rate.Per, err = Duration(per); err != nil {
+		return types.Rate{}, fmt.Errorf(`invalid rate period "%s"`, per)
+	}
+	if rate.Per <= 0 {
+		return types.Rate{}, fmt.Errorf(`invalid rate period "%s"`, per)
+	}
+	return rate, nil
+}
+
+func RateToString(v types.Rate) string {
+	per := DurationToString(v.Per)
+	for unit, period := range ratePeriods {
+		if v.Per == period {
+			per = unit
+		}
+	}
+	return strconv.Itoa(v.Count) + "/" + per
+}
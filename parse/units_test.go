@@ -0,0 +1,45 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enolgor/go-utils/parse/types"
+)
+
+func TestByteSize(t *testing.T) {
+	sizes := map[string]types.ByteSize{"512": 512, "512k": 512000, "10MB": 10000000, "1.5GiB": 1610612736, "2 KiB": 2048, "1kb": 1000}
+	for str, expected := range sizes {
+		v, err := ByteSize(str)
+		if err != nil || v != expected {
+			t.Errorf("%s: got %d %v", str, v, err)
+		}
+		if v, err = ByteSize(ByteSizeToString(expected)); err != nil || v != expected {
+			t.Errorf("%s: %s does not round-trip", str, ByteSizeToString(expected))
+		}
+	}
+	formatted := []string{ByteSizeToString(1610612736), ByteSizeToString(10000000), ByteSizeToString(1500), ByteSizeToString(1234567)}
+	if strings.Join(formatted, " ") != "1.5GiB 10MB 1.5KB 1234.567KB" {
+		t.Errorf("got %v", formatted)
+	}
+	for _, str := range []string{"10XB", "1.5", "1.5B", "-1KB"} {
+		if v, err := ByteSize(str); err == nil {
+			t.Errorf("%s: expected error, got %d", str, v)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	rates := map[string]types.Rate{"100/s": {Count: 100, Per: time.Second}, "5000/h": {Count: 5000, Per: time.Hour}, "10/30s": {Count: 10, Per: 30 * time.Second}}
+	for str, expected := range rates {
+		if v, err := Rate(str); err != nil || v != expected || RateToString(v) != str {
+			t.Errorf("%s: got %v %v", str, v, err)
+		}
+	}
+	for _, str := range []string{"100", "-5/s", "5/0s"} {
+		if v, err := Rate(str); err == nil {
+			t.Errorf("%s: expected error, got %v", str, v)
+		}
+	}
+}
@@ -27,3 +27,15 @@ func (*intValidators) EqOrGreaterThan(min int) func(i *int) error {
 		return nil
 	}
 }
+
+func (*intValidators) EqOrLessThan(max int) func(i *int) error {
+	return func(i *int) error {
+		if err := isPresent(i); err != nil {
+			return err
+		}
+		if *i > max {
+			return fmt.Errorf("value must be less or equal than %d", max)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,51 @@
+package validators
+
+import (
+	"fmt"
+
+	"github.com/enolgor/go-utils/parse"
+	"github.com/enolgor/go-utils/parse/types"
+)
+
+type byteSizeValidators struct{}
+type rateValidators struct{}
+
+var ByteSizes *byteSizeValidators = &byteSizeValidators{}
+var Rates *rateValidators = &rateValidators{}
+
+func (*byteSizeValidators) BetweenIncl(min, max types.ByteSize) func(v *types.ByteSize) error {
+	return func(v *types.ByteSize) error {
+		if err := isPresent(v); err != nil {
+			return err
+		}
+		if *v < min || *v > max {
+			return fmt.Errorf("size must be between %s and %s (incl.)", parse.ByteSizeToString(min), parse.ByteSizeToString(max))
+		}
+		return nil
+	}
+}
+
+func (*byteSizeValidators) AtMost(max types.ByteSize) func(v *types.ByteSize) error {
+	return func(v *types.ByteSize) error {
+		if err := isPresent(v); err != nil {
+			return err
+		}
+		if *v > max {
+			return fmt.Errorf("size must be at most %s", parse.ByteSizeToString(max))
+		}
+		return nil
+	}
+}
+
+// AtMost compares the rates per unit of time, so 100/s is more than 5000/m.
+func (*rateValidators) AtMost(max types.Rate) func(v *types.Rate) error {
+	return func(v *types.Rate) error {
+		if err := isPresent(v); err != nil {
+			return err
+		}
+		if v.Per <= 0 || float64(v.Count)/float64(v.Per) > float64(max.Count)/float64(max.Per) {
+			return fmt.Errorf("rate must be at most %s", parse.RateToString(max))
+		}
+		return nil
+	}
+}
@@ -2,8 +2,6 @@ package validators
 
 import (
 	"errors"
-
-	"github.com/enolgor/go-utils/parse"
 )
 
 type stringValidators struct{}
@@ -14,14 +12,14 @@ var Strings *stringValidators = &stringValidators{}
 var Ints *intValidators = &intValidators{}
 var HexBytes *hexValidators = &hexValidators{}
 
-func isPresent[P parse.Parseable](p *P) error {
+func isPresent[P any](p *P) error {
 	if p == nil {
 		return errors.New("nil value")
 	}
 	return nil
 }
 
-func All[P parse.Parseable](validators ...func(*P) error) func(*P) error {
+func All[P any](validators ...func(*P) error) func(*P) error {
 	return func(v *P) error {
 		var errs error
 		for i := range validators {
@@ -31,7 +29,7 @@ func All[P parse.Parseable](validators ...func(*P) error) func(*P) error {
 	}
 }
 
-func Any[P parse.Parseable](validators ...func(*P) error) func(*P) error {
+func Any[P any](validators ...func(*P) error) func(*P) error {
 	return func(v *P) error {
 		var errs error
 		for i := range validators {